@@ -0,0 +1,165 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/urfave/cli/v2"
+
+	"github.com/cdmerchant13/giterdone/config"
+	"github.com/cdmerchant13/giterdone/git"
+	"github.com/cdmerchant13/giterdone/manifest"
+	"github.com/cdmerchant13/giterdone/utils"
+)
+
+// runAdopt takes over an existing git repo -- a URL to clone or a local
+// clone to link -- as giterdone's backup repo, so users migrating from a
+// hand-managed dotfiles repo don't start from scratch. It reconciles the
+// config's include paths with what the repo already tracks and generates a
+// manifest from the current contents.
+func runAdopt(c *cli.Context) error {
+	target := c.Args().First()
+	if target == "" {
+		return fmt.Errorf("usage: adopt <path-or-url>")
+	}
+	configDir, err := config.GetConfigDir()
+	if err != nil {
+		return err
+	}
+	if err := utils.InitLogger(configDir, c.Bool("verbose")); err != nil {
+		return err
+	}
+	if err := git.CheckInstalled(); err != nil {
+		return err
+	}
+	cfg, err := config.LoadConfig()
+	if err != nil {
+		return fmt.Errorf("adopt needs an existing config (run the setup wizard first): %w", err)
+	}
+	repoDir, err := config.GetRepoDir()
+	if err != nil {
+		return err
+	}
+	if git.IsGitRepo(repoDir) {
+		return fmt.Errorf("a backup repo already exists at %s; adopt is for taking over a repo before the first backup", repoDir)
+	}
+
+	if strings.Contains(target, "://") || strings.HasPrefix(target, "git@") {
+		if !sameRemote(target, cfg.RepoURL) {
+			return fmt.Errorf("%s does not match the configured repo %s; update repo_url first", target, cfg.SanitizedRepoURL())
+		}
+		if err := git.CloneRepo(cfg.AuthenticatedRepoURL(), repoDir); err != nil {
+			return err
+		}
+	} else {
+		abs, err := filepath.Abs(target)
+		if err != nil {
+			return err
+		}
+		if !git.IsGitRepo(abs) {
+			return fmt.Errorf("%s is not a git repository", abs)
+		}
+		origin, err := git.RemoteOriginURL(abs)
+		if err != nil {
+			return fmt.Errorf("could not read origin remote of %s: %w", abs, err)
+		}
+		if !sameRemote(origin, cfg.RepoURL) {
+			return fmt.Errorf("origin of %s does not match the configured repo %s; update repo_url first", abs, cfg.SanitizedRepoURL())
+		}
+		// Link rather than move, so the user's existing checkout keeps
+		// working where they expect it.
+		if err := os.MkdirAll(filepath.Dir(repoDir), 0o755); err != nil {
+			return err
+		}
+		if err := os.Symlink(abs, repoDir); err != nil {
+			return err
+		}
+		utils.LogMessage("Linked backup repo %s -> %s", repoDir, abs)
+	}
+
+	tracked, err := git.LsFiles(repoDir)
+	if err != nil {
+		return err
+	}
+
+	// Anything the repo already tracks that the include paths don't cover
+	// would be dropped by the next backup; pull those paths into the config.
+	addedPaths := 0
+	for _, rel := range tracked {
+		if isBookkeepingFile(rel) {
+			continue
+		}
+		src := "/" + rel
+		if !coveredByIncludePaths(cfg, src) {
+			cfg.IncludePaths = append(cfg.IncludePaths, src)
+			addedPaths++
+		}
+	}
+	if addedPaths > 0 {
+		if err := config.SaveConfig(cfg); err != nil {
+			return err
+		}
+	}
+
+	m := manifest.New()
+	if cfg.HashAlgorithm != "" {
+		m.Algorithm = cfg.HashAlgorithm
+	}
+	for _, rel := range tracked {
+		if isBookkeepingFile(rel) {
+			continue
+		}
+		hash, err := manifest.HashFileWith(filepath.Join(repoDir, rel), m.Algorithm)
+		if err != nil {
+			utils.LogMessage("Could not hash %s: %v", rel, err)
+			continue
+		}
+		m.Entries[rel] = manifest.Entry{Hash: hash}
+	}
+	if err := m.Save(repoDir); err != nil {
+		return err
+	}
+
+	fmt.Printf("Adopted repo with %d tracked files; added %d include paths to the config. The manifest will be committed on the next backup.\n",
+		len(tracked), addedPaths)
+	return nil
+}
+
+// isBookkeepingFile reports whether a tracked path is one of giterdone's own
+// repo-internal files rather than backed-up user content.
+func isBookkeepingFile(rel string) bool {
+	return rel == ".gitignore" || rel == manifest.FileName || rel == xattrManifestName
+}
+
+// coveredByIncludePaths reports whether src falls under any configured
+// include path.
+func coveredByIncludePaths(cfg *config.Config, src string) bool {
+	for _, inc := range cfg.IncludePaths {
+		if src == inc || strings.HasPrefix(src, strings.TrimSuffix(inc, "/")+"/") {
+			return true
+		}
+	}
+	return false
+}
+
+// sameRemote compares two remote URLs ignoring scheme, embedded credentials,
+// SSH-vs-HTTPS form, and a trailing .git suffix.
+func sameRemote(a, b string) bool {
+	return normalizeRemote(a) == normalizeRemote(b)
+}
+
+// normalizeRemote reduces a remote URL to host/path for comparison.
+func normalizeRemote(u string) string {
+	for _, s := range []string{"https://", "http://", "ssh://"} {
+		u = strings.TrimPrefix(u, s)
+	}
+	if rest, ok := strings.CutPrefix(u, "git@"); ok {
+		u = strings.Replace(rest, ":", "/", 1)
+	}
+	if at := strings.LastIndex(u, "@"); at != -1 {
+		u = u[at+1:]
+	}
+	return strings.TrimSuffix(strings.TrimSuffix(u, "/"), ".git")
+}
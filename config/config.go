@@ -0,0 +1,687 @@
+package config
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"os"
+	"os/exec"
+	"os/user"
+	"path/filepath"
+	"strings"
+)
+
+// CommandSpec describes a command whose stdout is captured into the backup
+// repo on every run.
+type CommandSpec struct {
+	Name       string `json:"name"`
+	Cmd        string `json:"cmd"`
+	OutputFile string `json:"output_file"`
+}
+
+// Config holds everything giterdone needs to run a backup.
+type Config struct {
+	RepoURL               string   `json:"repo_url"`
+	PAT                   string   `json:"pat,omitempty"`
+	// PATSource selects where the PAT comes from: "" (the config file) or
+	// "keyring" (the OS keyring, so the config file holds no secret).
+	PATSource string `json:"pat_source,omitempty"`
+	// AuthStore is the preferred name for the same setting ("file" or
+	// "keyring"); LoadConfig normalizes it into PATSource, so either key
+	// works in the config file.
+	AuthStore string `json:"auth_store,omitempty"`
+	IncludePaths          []string `json:"include_paths"`
+	CommitMessageTemplate string   `json:"commit_message_template"`
+	// CommitMessageTemplateFile, when set, is read on every run and takes
+	// precedence over the inline template.
+	CommitMessageTemplateFile string `json:"commit_message_template_file,omitempty"`
+	Frequency                 string `json:"frequency"`
+	// Provider identifies the remote host type: "github", "gitlab", or
+	// "generic" for self-managed servers (bare repos over ssh://). Unset
+	// means it is detected from the repo URL.
+	Provider string        `json:"provider,omitempty"`
+	// AuthMethod selects how the remote is reached: "https" (PAT embedded in
+	// the URL) or "ssh" (the user's keys). Unset means it is inferred from
+	// the repo URL's scheme. The URL is rewritten between forms as needed.
+	AuthMethod string `json:"auth_method,omitempty"`
+	// SSHKeyPath pins SSH auth to a specific private key (via
+	// GIT_SSH_COMMAND), for custom-named or per-host keys that ssh would
+	// not try by default. Empty leaves key selection to ssh.
+	SSHKeyPath string `json:"ssh_key_path,omitempty"`
+	Commands []CommandSpec `json:"commands,omitempty"`
+	// MaxFileSizeBytes overrides the built-in 100MB per-file limit; files
+	// larger than this are excluded from backups. 0 keeps the default.
+	MaxFileSizeBytes int64 `json:"max_file_size_bytes,omitempty"`
+	// SizeOverrides maps a path prefix to a max file size in MB that
+	// overrides the global limit for files under that prefix.
+	SizeOverrides map[string]int `json:"size_overrides,omitempty"`
+	// ExcludePatterns adds the user's own exclude patterns (file or
+	// directory names, or globs like "*.env") on top of the built-in
+	// defaults.
+	ExcludePatterns []string `json:"exclude_patterns,omitempty"`
+	// IncludeOverrides force-includes files that an exclude pattern would
+	// otherwise drop; it beats every exclusion layer.
+	IncludeOverrides []string `json:"include_overrides,omitempty"`
+	// MaxDirSizeMB skips any directory whose cumulative contents exceed
+	// this size, catching trees of individually-small files (a .cache full
+	// of thumbnails) that the per-file limit never would. 0 disables the
+	// check.
+	MaxDirSizeMB int `json:"max_dir_size_mb,omitempty"`
+	// PathsByHost maps a hostname to that machine's include paths, so one
+	// shared config can back up different sets per machine. When the
+	// current hostname has an entry it replaces IncludePaths; otherwise the
+	// base list applies.
+	PathsByHost map[string][]string `json:"paths_by_host,omitempty"`
+	// CreateRepoIfMissing creates the remote repo via the provider API
+	// (using the PAT) when it does not exist yet. RepoVisibility controls
+	// the created repo's visibility and defaults to "private".
+	CreateRepoIfMissing bool   `json:"create_repo_if_missing,omitempty"`
+	RepoVisibility      string `json:"repo_visibility,omitempty"`
+	// Validators maps a filename glob to a command run against matching
+	// files before commit (the path is appended). A nonzero exit excludes
+	// the file, or aborts the run when ValidatorPolicy is "abort".
+	Validators      map[string]string `json:"validators,omitempty"`
+	ValidatorPolicy string            `json:"validator_policy,omitempty"`
+	// SecretScanPolicy controls the pre-commit secret scan: "warn", "abort",
+	// or "off". Unset defaults to "abort" for public repos and "warn"
+	// otherwise.
+	SecretScanPolicy string `json:"secret_scan_policy,omitempty"`
+	// DirtyRepoPolicy controls what happens when the backup repo has
+	// unexpected uncommitted changes at the start of a run: "warn"
+	// (default), "abort", "stash", or "commit-all".
+	DirtyRepoPolicy string `json:"dirty_repo_policy,omitempty"`
+	// OnlyIfOnline skips the run cleanly (exit 0) when the remote is not
+	// reachable, instead of failing the push on offline machines.
+	OnlyIfOnline bool `json:"only_if_online,omitempty"`
+	// SquashEveryNCommits, when positive, squashes the last N backup
+	// commits into one checkpoint commit every N commits and force-pushes
+	// with lease, bounding history growth deterministically. Explicit
+	// opt-in since it rewrites remote history.
+	SquashEveryNCommits int `json:"squash_every_n_commits,omitempty"`
+	// EmptyCommitHeartbeat makes every run commit even with no changes
+	// (--allow-empty), so monitoring via remote commit activity can prove
+	// the job ran. Off by default.
+	EmptyCommitHeartbeat bool `json:"empty_commit_heartbeat,omitempty"`
+	// MergeStrategies maps .gitattributes patterns to git merge drivers,
+	// e.g. {".bash_history": "union"} so append-only files merge without
+	// conflicts across machines. Rendered into a generated .gitattributes
+	// block alongside the .gitignore.
+	MergeStrategies map[string]string `json:"merge_strategies,omitempty"`
+	// NotifyWebhookURL, when set, receives a JSON POST with the outcome of
+	// each real backup run. NotifyFormat selects the payload shape:
+	// "generic" (the default) or "slack" for Slack incoming webhooks.
+	// Failures are always reported; set NotifyOnSuccess to also report
+	// successful runs.
+	NotifyWebhookURL string `json:"notify_webhook_url,omitempty"`
+	NotifyFormat     string `json:"notify_format,omitempty"`
+	NotifyOnSuccess  bool   `json:"notify_on_success,omitempty"`
+	// PushRateLimitKBps throttles pushes to roughly this upload rate using
+	// trickle when available. 0 means unlimited.
+	PushRateLimitKBps int `json:"push_rate_limit_kbps,omitempty"`
+	// NetworkRetries and NetworkRetryBaseSeconds tune the retry policy for
+	// network git operations (clone, fetch, push): up to NetworkRetries
+	// attempts with exponential backoff starting at the base delay. Zero
+	// values use the defaults (3 attempts, 2s).
+	NetworkRetries          int `json:"network_retries,omitempty"`
+	NetworkRetryBaseSeconds int `json:"network_retry_base_seconds,omitempty"`
+	// PushEveryMinutes decouples push cadence from scan cadence: commits
+	// still land locally every run, but a push only happens once this many
+	// minutes have passed since the last successful push. 0 pushes every
+	// run. Useful on metered links with a frequent scan schedule.
+	PushEveryMinutes int `json:"push_every_minutes,omitempty"`
+	// CommitPerPath makes one commit per include path instead of one mixed
+	// commit, so history stays navigable per project.
+	CommitPerPath bool `json:"commit_per_path,omitempty"`
+	// Branch is the branch backups are committed and pushed to, created on
+	// first use if needed. Empty means the repo's default branch.
+	Branch string `json:"branch,omitempty"`
+	// BranchPerHost backs each machine up to its own branch named after the
+	// (sanitized) hostname, for per-host isolation in a shared repo. It
+	// takes precedence over Branch.
+	BranchPerHost bool `json:"branch_per_host,omitempty"`
+	// FollowSymlinks copies symlink targets' contents instead of recording
+	// the links themselves. Off by default: for dotfiles the symlink itself
+	// is meaningful configuration, so links are stored as git symlink
+	// objects and recreated on restore.
+	FollowSymlinks bool `json:"follow_symlinks,omitempty"`
+	// PreserveXattrs captures each file's extended attributes into a
+	// sidecar manifest and reapplies them on restore, for metadata-exact
+	// restores (quarantine flags, SELinux contexts). Opt-in; filesystems
+	// without xattr support are skipped with a log line.
+	PreserveXattrs bool `json:"preserve_xattrs,omitempty"`
+	// CopyBufferKB sizes the buffer used when mirroring files into the
+	// repo. 0 means the built-in default; larger values can help on fast
+	// disks with very large files.
+	CopyBufferKB int `json:"copy_buffer_kb,omitempty"`
+	// HashWorkers bounds the worker pool used to hash file contents when
+	// DedupFiles is on. 0 means one worker per CPU.
+	HashWorkers int `json:"hash_workers,omitempty"`
+	// HashAlgorithm selects the digest used for the manifest and change
+	// detection: "sha256" (the default), "sha512" or "blake2b", for sites
+	// whose compliance rules mandate a specific algorithm.
+	HashAlgorithm string `json:"hash_algorithm,omitempty"`
+	// DedupFiles stores identical files once, recording duplicates in the
+	// manifest instead of mirroring redundant copies. Off by default since
+	// it complicates restore.
+	DedupFiles bool `json:"dedup_files,omitempty"`
+	// RepoSubpath nests mirrored files under this directory inside the
+	// repo. It is a template with {{.Year}}, {{.Month}}, {{.Day}} and
+	// {{.Hostname}} tokens, resolved at run time, so layouts like
+	// "snapshots/{{.Year}}/{{.Month}}" organize themselves.
+	RepoSubpath string `json:"repo_subpath,omitempty"`
+	// SingleRepoMode treats the single include path as the backup repo
+	// itself: giterdone runs `git add -A` there directly instead of
+	// mirroring files into a managed clone.
+	SingleRepoMode bool `json:"single_repo_mode,omitempty"`
+	// UseGitCheckIgnore delegates per-file exclusion decisions to
+	// `git check-ignore` for exact gitignore semantics.
+	UseGitCheckIgnore bool `json:"use_git_check_ignore,omitempty"`
+	// PATExpiryWarnDays warns when the PAT expires within N days. With
+	// PATExpiryFail set, an expiring token fails the run instead.
+	PATExpiryWarnDays int  `json:"pat_expiry_warn_days,omitempty"`
+	PATExpiryFail     bool `json:"pat_expiry_fail,omitempty"`
+	// SettleSeconds defers files modified within the last N seconds to the
+	// next run, so a file being written mid-scan isn't captured partially.
+	SettleSeconds int `json:"settle_seconds,omitempty"`
+	// LogFormat selects the log file encoding: "text" (the default) or
+	// "json" for one object per line, for shipping to log aggregators.
+	LogFormat string `json:"log_format,omitempty"`
+	// LogMaxSizeBytes and LogMaxBackups bound the log footprint: once
+	// giterdone.log exceeds the size limit it is rotated to a numbered
+	// backup, keeping at most LogMaxBackups old files. Zero values use the
+	// defaults (5MB, 3 backups).
+	LogMaxSizeBytes int64 `json:"log_max_size_bytes,omitempty"`
+	LogMaxBackups   int   `json:"log_max_backups,omitempty"`
+	// Scheduler selects how scheduled runs are installed: "cron" writes a
+	// crontab entry, "systemd" a user service and timer, "launchd" a macOS
+	// launch agent. Unset picks launchd on macOS and cron elsewhere.
+	Scheduler string `json:"scheduler,omitempty"`
+	// RunAsUser, when set and running as root, is the user git operations
+	// are performed as (their credentials, SSH keys, and HOME).
+	RunAsUser string `json:"run_as_user,omitempty"`
+	// StorageMode selects the storage backend: "" (default) commits file
+	// contents to the repo; "release-asset" uploads an encrypted tarball as
+	// a GitHub release asset instead, treating the remote as opaque
+	// encrypted object storage; "s3" uploads it to an S3-compatible bucket.
+	StorageMode string `json:"storage_mode,omitempty"`
+	// S3Bucket, S3Key, and S3Endpoint configure the "s3" storage mode. The
+	// key defaults to giterdone-backup.tar.gz.gpg; a versioned bucket keeps
+	// backup history. S3Endpoint overrides the endpoint for S3-compatible
+	// stores. Credentials come from the aws CLI's own configuration.
+	S3Bucket   string `json:"s3_bucket,omitempty"`
+	S3Key      string `json:"s3_key,omitempty"`
+	S3Endpoint string `json:"s3_endpoint,omitempty"`
+	// SafeMode hard-blocks every operation that would force-push or rewrite
+	// history, protecting repos shared across machines from one machine
+	// clobbering another's commits. Unset means true; history-rewriting
+	// features require explicitly setting it to false.
+	SafeMode *bool `json:"safe_mode,omitempty"`
+	// CommitAuthorName and CommitAuthorEmail set the git identity for
+	// backup commits, so scheduled runs work on boxes with no global
+	// user.name/user.email configured.
+	CommitAuthorName  string `json:"commit_author_name,omitempty"`
+	CommitAuthorEmail string `json:"commit_author_email,omitempty"`
+	// SigningKey GPG-signs every backup commit with this key ID, for users
+	// who need an auditable, verifiable backup history.
+	SigningKey string `json:"signing_key,omitempty"`
+	// PullBeforePush rebases the local repo onto the remote before each
+	// commit, so edits made directly on the remote (e.g. through the GitHub
+	// web UI) don't leave pushes rejected as non-fast-forward. Unset means
+	// true.
+	PullBeforePush *bool `json:"pull_before_push,omitempty"`
+	// GenerateGitignore controls whether giterdone writes the repo's
+	// .gitignore. Unset means true; set it to false to curate the file by
+	// hand. Exclusions still apply to the scan either way.
+	GenerateGitignore *bool `json:"generate_gitignore,omitempty"`
+}
+
+// ShouldGenerateGitignore reports whether the repo's .gitignore should be
+// (re)written, defaulting to true when unset.
+func (c *Config) ShouldGenerateGitignore() bool {
+	return c.GenerateGitignore == nil || *c.GenerateGitignore
+}
+
+// ShouldPullBeforePush reports whether the repo is rebased onto the remote
+// before committing, defaulting to true when unset.
+func (c *Config) ShouldPullBeforePush() bool {
+	return c.PullBeforePush == nil || *c.PullBeforePush
+}
+
+// IsSafeMode reports whether history-rewriting operations are blocked,
+// defaulting to true when unset.
+func (c *Config) IsSafeMode() bool {
+	return c.SafeMode == nil || *c.SafeMode
+}
+
+// ExpandUserPath expands $VAR/${VAR} environment references and a leading
+// ~, ~/ or ~user against the current environment. Configs store the raw
+// string and callers resolve at use time, so the same config works across
+// users. Unknown users and unset variables leave the path as close to the
+// input as possible rather than failing.
+func ExpandUserPath(p string) string {
+	p = os.ExpandEnv(p)
+	if p == "~" || strings.HasPrefix(p, "~/") {
+		if home, err := os.UserHomeDir(); err == nil {
+			return filepath.Join(home, strings.TrimPrefix(p[1:], "/"))
+		}
+		return p
+	}
+	if strings.HasPrefix(p, "~") {
+		name, rest, _ := strings.Cut(p[1:], "/")
+		if u, err := user.Lookup(name); err == nil {
+			return filepath.Join(u.HomeDir, rest)
+		}
+	}
+	return p
+}
+
+// configPathOverride, when set by --config or GITERDONE_CONFIG, points at an
+// arbitrary config file; the config directory (logs, state, repo clone)
+// follows it so a fully relocated setup stays self-contained.
+var configPathOverride string
+
+// SetConfigPathOverride points all config resolution at an explicit file
+// for this run. Empty leaves the default resolution in place.
+func SetConfigPathOverride(path string) {
+	configPathOverride = path
+}
+
+// GetConfigDir returns the giterdone config directory: the directory of an
+// explicit --config/GITERDONE_CONFIG file when one is set, otherwise
+// $XDG_CONFIG_HOME/giterdone, defaulting to ~/.config/giterdone.
+func GetConfigDir() (string, error) {
+	if configPathOverride != "" {
+		return filepath.Dir(configPathOverride), nil
+	}
+	if xdg := os.Getenv("XDG_CONFIG_HOME"); xdg != "" {
+		return filepath.Join(xdg, "giterdone"), nil
+	}
+	home, err := os.UserHomeDir()
+	if err != nil {
+		return "", fmt.Errorf("could not determine home directory: %w", err)
+	}
+	return filepath.Join(home, ".config", "giterdone"), nil
+}
+
+// GetConfigPath returns the full path to the config file. An explicit
+// --config/GITERDONE_CONFIG path wins outright; otherwise a gpg-encrypted
+// config (config.json.gpg) takes precedence when present, and a config
+// left behind by the old "mybackup" install is migrated into place.
+func GetConfigPath() (string, error) {
+	if configPathOverride != "" {
+		return configPathOverride, nil
+	}
+	dir, err := GetConfigDir()
+	if err != nil {
+		return "", err
+	}
+	encrypted := filepath.Join(dir, profileConfigName()+".gpg")
+	if _, err := os.Stat(encrypted); err == nil {
+		return encrypted, nil
+	}
+	path := filepath.Join(dir, profileConfigName())
+	migrateLegacyConfig(path)
+	return path, nil
+}
+
+// migrateLegacyConfig copies a config from the pre-rename
+// ~/.config/mybackup location to the current path when the current one does
+// not exist yet, so upgrades keep working without re-running the wizard.
+// The legacy file is left behind untouched.
+func migrateLegacyConfig(path string) {
+	if _, err := os.Stat(path); err == nil {
+		return
+	}
+	home, err := os.UserHomeDir()
+	if err != nil {
+		return
+	}
+	legacy := filepath.Join(home, ".config", "mybackup", "config.json")
+	data, err := os.ReadFile(legacy)
+	if err != nil {
+		return
+	}
+	if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+		return
+	}
+	if err := writeFileAtomic(path, data, 0o600); err != nil {
+		return
+	}
+	fmt.Fprintf(os.Stderr, "Migrated config from legacy %s; the old file was left in place.\n", legacy)
+}
+
+// GetRepoDir returns the directory the backup repo is cloned into.
+func GetRepoDir() (string, error) {
+	dir, err := GetConfigDir()
+	if err != nil {
+		return "", err
+	}
+	return filepath.Join(dir, profileRepoDirName()), nil
+}
+
+// LoadConfig reads and parses the config file. A missing file is reported
+// via os.IsNotExist on the returned error so callers can trigger setup.
+func LoadConfig() (*Config, error) {
+	path, err := GetConfigPath()
+	if err != nil {
+		return nil, err
+	}
+	var data []byte
+	if strings.HasSuffix(path, ".gpg") {
+		data, err = gpgDecrypt(path)
+	} else {
+		data, err = os.ReadFile(path)
+	}
+	if err != nil {
+		return nil, err
+	}
+	var cfg Config
+	if err := json.Unmarshal(data, &cfg); err != nil {
+		return nil, fmt.Errorf("could not parse config file %s: %w", path, err)
+	}
+	if cfg.PATSource == "" && cfg.AuthStore != "" && cfg.AuthStore != "file" {
+		cfg.PATSource = cfg.AuthStore
+	}
+	if cfg.PATSource == "keyring" {
+		pat, err := keyringLookup(cfg.RepoURL)
+		switch {
+		case err == nil:
+			cfg.PAT = pat
+		case cfg.PAT != "":
+			// Headless machine without a keyring: fall back to the config
+			// file's PAT rather than failing the run.
+			fmt.Fprintf(os.Stderr, "Warning: keyring unavailable (%v); using the PAT from the config file\n", err)
+		default:
+			return nil, fmt.Errorf("pat_source is \"keyring\" but the keyring is unavailable and the config holds no PAT: %w", err)
+		}
+	}
+	return &cfg, nil
+}
+
+// SaveConfig writes the config back to disk as indented JSON.
+func SaveConfig(cfg *Config) error {
+	if cfg.CommitMessageTemplateFile != "" {
+		if _, err := os.Stat(cfg.CommitMessageTemplateFile); err != nil {
+			return fmt.Errorf("commit_message_template_file: %w", err)
+		}
+	}
+	path, err := GetConfigPath()
+	if err != nil {
+		return err
+	}
+	if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+		return err
+	}
+	// A keyring-sourced PAT lives only in the keyring; never write the
+	// in-memory copy back to the config file.
+	toSave := cfg
+	if cfg.PATSource == "keyring" {
+		clean := *cfg
+		clean.PAT = ""
+		toSave = &clean
+	}
+	data, err := json.MarshalIndent(toSave, "", "  ")
+	if err != nil {
+		return err
+	}
+	if strings.HasSuffix(path, ".gpg") {
+		return gpgEncrypt(path, data)
+	}
+	return writeFileAtomic(path, data, 0o600)
+}
+
+// writeFileAtomic writes data to a temp file in the same directory, fsyncs
+// it, and renames it into place so a crash mid-write can never leave a
+// truncated file behind.
+func writeFileAtomic(path string, data []byte, perm os.FileMode) error {
+	tmp, err := os.CreateTemp(filepath.Dir(path), filepath.Base(path)+".tmp*")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(tmp.Name())
+	if _, err := tmp.Write(data); err != nil {
+		tmp.Close()
+		return err
+	}
+	if err := tmp.Chmod(perm); err != nil {
+		tmp.Close()
+		return err
+	}
+	if err := tmp.Sync(); err != nil {
+		tmp.Close()
+		return err
+	}
+	if err := tmp.Close(); err != nil {
+		return err
+	}
+	return os.Rename(tmp.Name(), path)
+}
+
+// gpgDecrypt reads an encrypted config via gpg, relying on the user's agent
+// for the key.
+func gpgDecrypt(path string) ([]byte, error) {
+	cmd := exec.Command("gpg", "--quiet", "--decrypt", path)
+	var stderr bytes.Buffer
+	cmd.Stderr = &stderr
+	out, err := cmd.Output()
+	if err != nil {
+		return nil, fmt.Errorf("could not decrypt %s (is the gpg agent running?): %s: %w",
+			path, strings.TrimSpace(stderr.String()), err)
+	}
+	return out, nil
+}
+
+// gpgEncrypt pipes the config JSON through gpg and writes the result to path.
+func gpgEncrypt(path string, data []byte) error {
+	cmd := exec.Command("gpg", "--quiet", "--yes", "--encrypt", "--default-recipient-self", "--output", path)
+	cmd.Stdin = bytes.NewReader(data)
+	var stderr bytes.Buffer
+	cmd.Stderr = &stderr
+	if err := cmd.Run(); err != nil {
+		return fmt.Errorf("could not encrypt config (is the gpg agent running?): %s: %w",
+			strings.TrimSpace(stderr.String()), err)
+	}
+	return nil
+}
+
+// authMethodOverride, when set by --prefer-https/--prefer-ssh, wins over the
+// configured AuthMethod for this invocation only; saved config is untouched.
+var authMethodOverride string
+
+// SetAuthMethodOverride overrides the auth method for the current run.
+func SetAuthMethodOverride(method string) {
+	authMethodOverride = method
+}
+
+// EffectiveAuthMethod returns the auth method in effect: the run-scoped
+// override, then the configured AuthMethod, then inference from the URL.
+func (c *Config) EffectiveAuthMethod() string {
+	if authMethodOverride != "" {
+		return authMethodOverride
+	}
+	if c.AuthMethod != "" {
+		return c.AuthMethod
+	}
+	if strings.HasPrefix(c.RepoURL, "https://") || strings.HasPrefix(c.RepoURL, "http://") {
+		return "https"
+	}
+	return "ssh"
+}
+
+// AuthenticatedRepoURL returns the URL git should use to reach the remote,
+// rewritten to the effective auth method's form: HTTPS with the PAT
+// embedded, or the SSH equivalent.
+func (c *Config) AuthenticatedRepoURL() string {
+	switch c.EffectiveAuthMethod() {
+	case "https":
+		url := httpsFormURL(c.RepoURL)
+		if c.PAT != "" && strings.HasPrefix(url, "https://") {
+			return "https://oauth2:" + c.PAT + "@" + strings.TrimPrefix(url, "https://")
+		}
+		return url
+	case "ssh":
+		return sshFormURL(c.RepoURL)
+	}
+	return c.RepoURL
+}
+
+// httpsFormURL rewrites a git@host:path SSH URL to its HTTPS equivalent.
+// URLs already using a scheme are returned unchanged.
+func httpsFormURL(url string) string {
+	if strings.HasPrefix(url, "git@") {
+		if host, path, ok := strings.Cut(strings.TrimPrefix(url, "git@"), ":"); ok {
+			return "https://" + host + "/" + path
+		}
+	}
+	return url
+}
+
+// sshFormURL rewrites an HTTPS URL to the scp-like git@host:path form.
+// SSH-form URLs are returned unchanged.
+func sshFormURL(url string) string {
+	for _, scheme := range []string{"https://", "http://"} {
+		if strings.HasPrefix(url, scheme) {
+			if host, path, ok := strings.Cut(strings.TrimPrefix(url, scheme), "/"); ok {
+				return "git@" + host + ":" + path
+			}
+		}
+	}
+	return url
+}
+
+// SanitizedRepoURL returns the repo URL with any embedded credentials
+// stripped, safe to print, log, or share. It undoes both the oauth2:PAT form
+// giterdone embeds and any user:password@ the user pasted into the config.
+func (c *Config) SanitizedRepoURL() string {
+	u := c.RepoURL
+	scheme := ""
+	for _, s := range []string{"https://", "http://"} {
+		if strings.HasPrefix(u, s) {
+			scheme, u = s, strings.TrimPrefix(u, s)
+			break
+		}
+	}
+	if scheme == "" {
+		return c.RepoURL
+	}
+	if at := strings.LastIndex(u, "@"); at != -1 {
+		u = u[at+1:]
+	}
+	return scheme + u
+}
+
+// CommitTemplate returns the commit message template, re-reading the
+// template file on every call when one is configured so edits apply without
+// touching the config.
+func (c *Config) CommitTemplate() (string, error) {
+	if c.CommitMessageTemplateFile == "" {
+		return c.CommitMessageTemplate, nil
+	}
+	data, err := os.ReadFile(c.CommitMessageTemplateFile)
+	if err != nil {
+		return "", fmt.Errorf("could not read commit message template file: %w", err)
+	}
+	return strings.TrimRight(string(data), "\n"), nil
+}
+
+// EffectiveProvider returns the configured provider, falling back to
+// detection from the repo URL. Unrecognized hosts are "generic".
+func (c *Config) EffectiveProvider() string {
+	if c.Provider != "" {
+		return c.Provider
+	}
+	return DetectProvider(c.RepoURL)
+}
+
+// DetectProvider guesses the provider from a repo URL.
+func DetectProvider(url string) string {
+	switch {
+	case strings.Contains(url, "github.com"):
+		return "github"
+	case strings.Contains(url, "gitlab"):
+		return "gitlab"
+	default:
+		return "generic"
+	}
+}
+
+// DetectSSHKeys returns the standard private keys present in ~/.ssh, in
+// order of preference, so the wizard can offer what is actually there
+// instead of assuming id_rsa.
+func DetectSSHKeys() []string {
+	home, err := os.UserHomeDir()
+	if err != nil {
+		return nil
+	}
+	var keys []string
+	for _, name := range []string{"id_ed25519", "id_ecdsa", "id_rsa"} {
+		path := filepath.Join(home, ".ssh", name)
+		if _, err := os.Stat(path); err == nil {
+			keys = append(keys, path)
+		}
+	}
+	return keys
+}
+
+// SuggestSSH reports whether the wizard should default to an SSH remote.
+func SuggestSSH() bool {
+	return len(DetectSSHKeys()) > 0
+}
+
+// ApplyHostPaths swaps in the include-path set for the current hostname when
+// PathsByHost has one, falling back to the base IncludePaths otherwise.
+func (c *Config) ApplyHostPaths() error {
+	if len(c.PathsByHost) == 0 {
+		return nil
+	}
+	hostname, err := os.Hostname()
+	if err != nil {
+		return err
+	}
+	if paths, ok := c.PathsByHost[hostname]; ok {
+		c.IncludePaths = paths
+	}
+	return nil
+}
+
+// MergePathsFile merges ~/.giterdone-paths into IncludePaths when the file
+// exists: one path per line, "#" comments, and "!" prefix lines removing a
+// path from the merged set. It is re-read on every run.
+func (c *Config) MergePathsFile() error {
+	home, err := os.UserHomeDir()
+	if err != nil {
+		return err
+	}
+	data, err := os.ReadFile(filepath.Join(home, ".giterdone-paths"))
+	if os.IsNotExist(err) {
+		return nil
+	} else if err != nil {
+		return err
+	}
+
+	merged := append([]string{}, c.IncludePaths...)
+	negated := map[string]bool{}
+	for _, line := range strings.Split(string(data), "\n") {
+		line = strings.TrimSpace(line)
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+		if strings.HasPrefix(line, "!") {
+			negated[strings.TrimSpace(strings.TrimPrefix(line, "!"))] = true
+			continue
+		}
+		merged = append(merged, line)
+	}
+
+	var result []string
+	seen := map[string]bool{}
+	for _, p := range merged {
+		if negated[p] || seen[p] {
+			continue
+		}
+		seen[p] = true
+		result = append(result, p)
+	}
+	c.IncludePaths = result
+	return nil
+}
@@ -0,0 +1,81 @@
+package config
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestWriteFileAtomicSurvivesPartialWrite(t *testing.T) {
+	dir := t.TempDir()
+	path := filepath.Join(dir, "config.json")
+
+	if err := writeFileAtomic(path, []byte(`{"good": true}`), 0o600); err != nil {
+		t.Fatal(err)
+	}
+
+	// Simulate a crash mid-write: a stale temp file from an interrupted run
+	// must not affect the real file's contents.
+	if err := os.WriteFile(path+".tmp-stale", []byte(`{"trunc`), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	got, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if string(got) != `{"good": true}` {
+		t.Errorf("config corrupted: %q", got)
+	}
+
+	// A successful rewrite replaces the content completely and leaves no
+	// temp file from its own write behind.
+	if err := writeFileAtomic(path, []byte(`{"good": false}`), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	got, _ = os.ReadFile(path)
+	if string(got) != `{"good": false}` {
+		t.Errorf("rewrite did not take effect: %q", got)
+	}
+	matches, _ := filepath.Glob(filepath.Join(dir, "config.json.tmp*"))
+	for _, m := range matches {
+		if m != path+".tmp-stale" {
+			t.Errorf("temp file left behind: %s", m)
+		}
+	}
+}
+
+func TestDetectProvider(t *testing.T) {
+	cases := map[string]string{
+		"https://github.com/user/repo.git":     "github",
+		"git@github.com:user/repo.git":         "github",
+		"https://gitlab.example.com/u/r.git":   "gitlab",
+		"ssh://backup@host/srv/git/backup.git": "generic",
+		"git@myserver.local:backups/dotfiles":  "generic",
+	}
+	for url, want := range cases {
+		if got := DetectProvider(url); got != want {
+			t.Errorf("DetectProvider(%q) = %q, want %q", url, got, want)
+		}
+	}
+}
+
+func TestExpandUserPath(t *testing.T) {
+	home, err := os.UserHomeDir()
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Setenv("GITERDONE_TEST_DIR", "/opt/data")
+	cases := []struct{ in, want string }{
+		{"~", home},
+		{"~/.bashrc", filepath.Join(home, ".bashrc")},
+		{"$GITERDONE_TEST_DIR/foo", "/opt/data/foo"},
+		{"${GITERDONE_TEST_DIR}/bar", "/opt/data/bar"},
+		{"/etc/hosts", "/etc/hosts"},
+		{"~no-such-user-xyz/file", "~no-such-user-xyz/file"},
+	}
+	for _, c := range cases {
+		if got := ExpandUserPath(c.in); got != c.want {
+			t.Errorf("ExpandUserPath(%q) = %q, want %q", c.in, got, c.want)
+		}
+	}
+}
@@ -0,0 +1,98 @@
+package config
+
+import (
+	"bytes"
+	"fmt"
+	"os/exec"
+	"runtime"
+	"strings"
+)
+
+// keyringService identifies giterdone's entries in the OS keyring;
+// legacyKeyringAccount is the shared account name used before PATs were
+// keyed per repo.
+const (
+	keyringService       = "giterdone"
+	legacyKeyringAccount = "pat"
+)
+
+// keyringAccountFor returns the keyring account for a repo, so configs
+// pointing at different remotes keep separate tokens.
+func keyringAccountFor(repoURL string) string {
+	if repoURL == "" {
+		return legacyKeyringAccount
+	}
+	return (&Config{RepoURL: repoURL}).SanitizedRepoURL()
+}
+
+// The keyring is driven by shelling out to the platform's CLI -- secret-tool
+// (libsecret) on Linux, security on macOS -- the same way gpg and crontab
+// are, avoiding a daemon-protocol dependency.
+
+// KeyringAvailable reports whether a usable keyring CLI is present.
+func KeyringAvailable() bool {
+	switch runtime.GOOS {
+	case "darwin":
+		_, err := exec.LookPath("security")
+		return err == nil
+	default:
+		_, err := exec.LookPath("secret-tool")
+		return err == nil
+	}
+}
+
+// KeyringStore saves the PAT in the OS keyring under the account for the
+// given repo.
+func KeyringStore(repoURL, pat string) error {
+	account := keyringAccountFor(repoURL)
+	var cmd *exec.Cmd
+	switch runtime.GOOS {
+	case "darwin":
+		cmd = exec.Command("security", "add-generic-password", "-U",
+			"-s", keyringService, "-a", account, "-w", pat)
+	default:
+		cmd = exec.Command("secret-tool", "store", "--label", "giterdone PAT",
+			"service", keyringService, "account", account)
+		cmd.Stdin = strings.NewReader(pat)
+	}
+	var stderr bytes.Buffer
+	cmd.Stderr = &stderr
+	if err := cmd.Run(); err != nil {
+		return fmt.Errorf("could not store PAT in keyring: %s: %w",
+			strings.TrimSpace(stderr.String()), err)
+	}
+	return nil
+}
+
+// keyringLookup fetches the PAT for the given repo from the OS keyring,
+// falling back to the legacy shared account so entries written by older
+// versions keep working.
+func keyringLookup(repoURL string) (string, error) {
+	pat, err := keyringLookupAccount(keyringAccountFor(repoURL))
+	if err != nil && repoURL != "" {
+		if legacy, legacyErr := keyringLookupAccount(legacyKeyringAccount); legacyErr == nil {
+			return legacy, nil
+		}
+	}
+	return pat, err
+}
+
+func keyringLookupAccount(account string) (string, error) {
+	var cmd *exec.Cmd
+	switch runtime.GOOS {
+	case "darwin":
+		cmd = exec.Command("security", "find-generic-password",
+			"-s", keyringService, "-a", account, "-w")
+	default:
+		cmd = exec.Command("secret-tool", "lookup",
+			"service", keyringService, "account", account)
+	}
+	var stderr bytes.Buffer
+	cmd.Stderr = &stderr
+	out, err := cmd.Output()
+	if err != nil {
+		return "", fmt.Errorf("could not read PAT from keyring: %s: %w",
+			strings.TrimSpace(stderr.String()), err)
+	}
+	return strings.TrimSpace(string(out)), nil
+}
@@ -0,0 +1,76 @@
+package config
+
+import (
+	"os"
+	"sort"
+	"strings"
+)
+
+// activeProfile names the profile whose config and repo paths apply. Empty
+// means the default profile (config.json, repo). Set once at startup, like
+// the run-as identity in the git package.
+var activeProfile string
+
+// SetActiveProfile routes GetConfigPath and GetRepoDir at the named
+// profile's files (config.<name>.json and repo-<name>). An empty name
+// selects the default profile.
+func SetActiveProfile(name string) {
+	activeProfile = name
+}
+
+// ActiveProfile returns the current profile name, "" for the default.
+func ActiveProfile() string {
+	return activeProfile
+}
+
+// ListProfiles returns the names of every named profile in the config dir,
+// i.e. every config.<name>.json (or .json.gpg), sorted. The default
+// config.json is not included.
+func ListProfiles() ([]string, error) {
+	dir, err := GetConfigDir()
+	if err != nil {
+		return nil, err
+	}
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return nil, nil
+		}
+		return nil, err
+	}
+	seen := map[string]bool{}
+	for _, e := range entries {
+		name := strings.TrimSuffix(e.Name(), ".gpg")
+		if !strings.HasPrefix(name, "config.") || !strings.HasSuffix(name, ".json") {
+			continue
+		}
+		profile := strings.TrimSuffix(strings.TrimPrefix(name, "config."), ".json")
+		if profile != "" {
+			seen[profile] = true
+		}
+	}
+	var profiles []string
+	for p := range seen {
+		profiles = append(profiles, p)
+	}
+	sort.Strings(profiles)
+	return profiles, nil
+}
+
+// profileConfigName returns the config file base name for the active
+// profile, without the .gpg suffix.
+func profileConfigName() string {
+	if activeProfile == "" {
+		return "config.json"
+	}
+	return "config." + activeProfile + ".json"
+}
+
+// profileRepoDirName returns the repo directory base name for the active
+// profile.
+func profileRepoDirName() string {
+	if activeProfile == "" {
+		return "repo"
+	}
+	return "repo-" + activeProfile
+}
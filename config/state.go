@@ -0,0 +1,78 @@
+package config
+
+import (
+	"encoding/json"
+	"os"
+	"path/filepath"
+)
+
+// State holds run-to-run bookkeeping that is not user configuration, such as
+// the repo sizes observed on the previous run.
+type State struct {
+	LastGitDirSize   int64 `json:"last_git_dir_size"`
+	LastWorkTreeSize int64 `json:"last_work_tree_size"`
+	// CommitsSinceSquash counts backup commits since the last squash, for
+	// the squash_every_n_commits checkpointing.
+	CommitsSinceSquash int `json:"commits_since_squash,omitempty"`
+	// FileHashes caches each file's content hash keyed by path, with the
+	// size and mtime it was computed at, so unchanged files skip rehashing
+	// on the next run.
+	FileHashes map[string]FileHash `json:"file_hashes,omitempty"`
+	// HashAlgorithm records which digest FileHashes was computed with; a
+	// config change invalidates the whole cache.
+	HashAlgorithm string `json:"hash_algorithm,omitempty"`
+	// LastPushUnix is when the last successful push finished, driving the
+	// push_every_minutes deferral.
+	LastPushUnix int64 `json:"last_push_unix,omitempty"`
+}
+
+// FileHash records a content hash and the file metadata it was computed at.
+type FileHash struct {
+	Size        int64  `json:"size"`
+	ModTimeUnix int64  `json:"mtime_unix"`
+	Hash        string `json:"hash"`
+}
+
+// GetStatePath returns the full path to the state file.
+func GetStatePath() (string, error) {
+	dir, err := GetConfigDir()
+	if err != nil {
+		return "", err
+	}
+	return filepath.Join(dir, "state.json"), nil
+}
+
+// LoadState reads the state file. A missing file yields a zero State.
+func LoadState() (*State, error) {
+	path, err := GetStatePath()
+	if err != nil {
+		return nil, err
+	}
+	data, err := os.ReadFile(path)
+	if os.IsNotExist(err) {
+		return &State{}, nil
+	} else if err != nil {
+		return nil, err
+	}
+	var st State
+	if err := json.Unmarshal(data, &st); err != nil {
+		return nil, err
+	}
+	return &st, nil
+}
+
+// SaveState writes the state file.
+func SaveState(st *State) error {
+	path, err := GetStatePath()
+	if err != nil {
+		return err
+	}
+	if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+		return err
+	}
+	data, err := json.MarshalIndent(st, "", "  ")
+	if err != nil {
+		return err
+	}
+	return writeFileAtomic(path, data, 0o644)
+}
@@ -0,0 +1,185 @@
+package cron
+
+import (
+	"fmt"
+	"os/exec"
+	"strings"
+	"time"
+
+	"github.com/cdmerchant13/giterdone/utils"
+)
+
+// cronComment marks the giterdone entry in the user's crontab so it can be
+// found and replaced on reinstall.
+const cronComment = "# Giterdone backup job"
+
+// frequencyToCronSpec translates a friendly frequency name into a cron spec.
+func frequencyToCronSpec(frequency string) (string, error) {
+	switch strings.ToLower(frequency) {
+	case "hourly":
+		return "0 * * * *", nil
+	case "daily":
+		return "0 2 * * *", nil
+	case "weekly":
+		return "0 2 * * 0", nil
+	default:
+		// Not a known name: accept a raw 5-field cron spec, validated so a
+		// typo fails here with a field-level message instead of cryptically
+		// in crontab.
+		if err := ValidateSpec(frequency); err != nil {
+			return "", fmt.Errorf("unknown frequency %q (expected hourly, daily, weekly, or a cron spec: %v)", frequency, err)
+		}
+		return frequency, nil
+	}
+}
+
+// InstallCronJob writes (or replaces) the giterdone entry in the user's
+// crontab for the given frequency. When the installed entry already matches
+// the desired one, the crontab is left untouched: rewriting it on every run
+// is needless churn on frequent schedules.
+func InstallCronJob(frequency, appPath string) error {
+	spec, err := frequencyToCronSpec(frequency)
+	if err != nil {
+		return err
+	}
+	entry := fmt.Sprintf("%s %s", spec, appPath)
+
+	out, _ := exec.Command("crontab", "-l").Output()
+	lines := strings.Split(string(out), "\n")
+	if existingGiterdoneEntry(lines) == entry {
+		utils.LogMessage("Cron schedule unchanged (%s); crontab not rewritten", spec)
+		return nil
+	}
+
+	lines = removeExistingGiterdoneJob(lines)
+	lines = append(lines, cronComment, entry)
+	if err := writeCrontab(lines); err != nil {
+		return err
+	}
+	if err := verifyCronJob(spec, appPath); err != nil {
+		return err
+	}
+	utils.LogMessage("Installed cron job: %s (next run %s)", entry,
+		nextRunTime(frequency).Format("2006-01-02 15:04"))
+	for _, line := range NextRunsSummary(frequency, 3) {
+		utils.LogMessage("%s", line)
+	}
+	return nil
+}
+
+// NextRunsSummary renders the next n fire times for a frequency (or raw
+// cron spec) in the local timezone, one line per run, so users can confirm
+// the schedule is interpreted as they expect -- including across DST
+// boundaries, which the minute-stepping parser handles naturally. An
+// unparseable frequency yields no lines rather than an error; callers use
+// this purely for display.
+func NextRunsSummary(frequency string, n int) []string {
+	_, times, err := PreviewSchedule(frequency, n)
+	if err != nil {
+		return nil
+	}
+	lines := make([]string, len(times))
+	for i, t := range times {
+		lines[i] = fmt.Sprintf("Next run %d: %s", i+1, t.Local().Format("Mon 2006-01-02 15:04 MST"))
+	}
+	return lines
+}
+
+// RemoveCronJob strips the giterdone entry from the user's crontab and
+// returns the removed entry, or "" when none was installed (which is not an
+// error, so uninstall stays idempotent).
+func RemoveCronJob() (string, error) {
+	out, _ := exec.Command("crontab", "-l").Output()
+	lines := strings.Split(string(out), "\n")
+	entry := existingGiterdoneEntry(lines)
+	if entry == "" {
+		return "", nil
+	}
+	if err := writeCrontab(removeExistingGiterdoneJob(lines)); err != nil {
+		return "", err
+	}
+	utils.LogMessage("Removed cron job: %s", entry)
+	return entry, nil
+}
+
+// writeCrontab replaces the user's crontab with the given lines.
+func writeCrontab(lines []string) error {
+	cmd := exec.Command("crontab", "-")
+	cmd.Stdin = strings.NewReader(strings.Join(lines, "\n") + "\n")
+	if combined, err := cmd.CombinedOutput(); err != nil {
+		return fmt.Errorf("could not install cron job: %s: %w", strings.TrimSpace(string(combined)), err)
+	}
+	return nil
+}
+
+// existingGiterdoneEntry returns the installed giterdone crontab entry (the
+// line after the marker comment), or "" when none is installed.
+func existingGiterdoneEntry(lines []string) string {
+	for i, line := range lines {
+		if strings.TrimSpace(line) == cronComment && i+1 < len(lines) {
+			return strings.TrimSpace(lines[i+1])
+		}
+	}
+	return ""
+}
+
+// verifyCronJob re-reads the crontab and confirms the giterdone entry was
+// actually written, catching restricted systems where `crontab -` fails
+// silently.
+func verifyCronJob(spec, appPath string) error {
+	out, err := exec.Command("crontab", "-l").Output()
+	if err != nil {
+		return fmt.Errorf("could not re-read crontab to verify installation: %w", err)
+	}
+	expected := fmt.Sprintf("%s %s", spec, appPath)
+	for _, line := range strings.Split(string(out), "\n") {
+		if strings.TrimSpace(line) == expected {
+			return nil
+		}
+	}
+	return fmt.Errorf("cron job was not found after writing the crontab; crontab writes may be restricted on this system")
+}
+
+// nextRunTime returns when the job for the given frequency will next fire.
+func nextRunTime(frequency string) time.Time {
+	now := time.Now()
+	switch strings.ToLower(frequency) {
+	case "hourly":
+		return now.Truncate(time.Hour).Add(time.Hour)
+	case "weekly":
+		// 02:00 on the coming Sunday.
+		next := time.Date(now.Year(), now.Month(), now.Day(), 2, 0, 0, 0, now.Location())
+		for next.Weekday() != time.Sunday || !next.After(now) {
+			next = next.AddDate(0, 0, 1)
+		}
+		return next
+	default: // daily at 02:00
+		next := time.Date(now.Year(), now.Month(), now.Day(), 2, 0, 0, 0, now.Location())
+		if !next.After(now) {
+			next = next.AddDate(0, 0, 1)
+		}
+		return next
+	}
+}
+
+// removeExistingGiterdoneJob strips any previous giterdone entry (the marker
+// comment and the line after it) from a crontab listing.
+func removeExistingGiterdoneJob(lines []string) []string {
+	var kept []string
+	skipNext := false
+	for _, line := range lines {
+		if skipNext {
+			skipNext = false
+			continue
+		}
+		if strings.TrimSpace(line) == cronComment {
+			skipNext = true
+			continue
+		}
+		if strings.TrimSpace(line) == "" {
+			continue
+		}
+		kept = append(kept, line)
+	}
+	return kept
+}
@@ -0,0 +1,161 @@
+package cron
+
+import (
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+
+	"github.com/cdmerchant13/giterdone/utils"
+)
+
+// launchdScheduler schedules runs with a launchd user agent, the supported
+// mechanism on macOS where crontab is deprecated.
+type launchdScheduler struct{}
+
+// launchdLabel is the agent's reverse-DNS label and plist base name.
+const launchdLabel = "com.giterdone.backup"
+
+// launchdPlistPath returns the user LaunchAgents plist path.
+func launchdPlistPath() (string, error) {
+	home, err := os.UserHomeDir()
+	if err != nil {
+		return "", fmt.Errorf("could not determine home directory: %w", err)
+	}
+	return filepath.Join(home, "Library", "LaunchAgents", launchdLabel+".plist"), nil
+}
+
+// launchdKeys maps cron field positions onto StartCalendarInterval keys.
+// Both sides agree on the value ranges, including Sunday as weekday 0.
+var launchdKeys = [5]string{"Minute", "Hour", "Day", "Month", "Weekday"}
+
+// maxCalendarDicts bounds how many StartCalendarInterval entries a cron spec
+// may expand into; a spec denser than this belongs on the crontab backend,
+// not in a plist.
+const maxCalendarDicts = 100
+
+// frequencyToCalendarInterval translates a frequency (or raw cron spec) into
+// the StartCalendarInterval dictionaries matching the cron backend's times:
+// one dictionary per combination of the spec's restricted fields, with
+// wildcard fields omitted so launchd treats them as "any". Cron's rule that
+// a restricted day-of-month OR day-of-week may match becomes separate Day
+// and Weekday entries.
+func frequencyToCalendarInterval(frequency string) (string, error) {
+	spec, err := frequencyToCronSpec(frequency)
+	if err != nil {
+		return "", err
+	}
+	fields, err := specFieldValues(spec)
+	if err != nil {
+		return "", err
+	}
+	variants := [][5][]int{fields}
+	if fields[2] != nil && fields[4] != nil {
+		domOnly, dowOnly := fields, fields
+		domOnly[4], dowOnly[2] = nil, nil
+		variants = [][5][]int{domOnly, dowOnly}
+	}
+
+	var b strings.Builder
+	total := 0
+	for _, v := range variants {
+		// Cartesian product of the restricted fields; -1 marks "any".
+		combos := [][5]int{{-1, -1, -1, -1, -1}}
+		for i, vals := range v {
+			if vals == nil {
+				continue
+			}
+			var next [][5]int
+			for _, c := range combos {
+				for _, val := range vals {
+					c[i] = val
+					next = append(next, c)
+				}
+			}
+			combos = next
+			if total+len(combos) > maxCalendarDicts {
+				return "", fmt.Errorf("cron spec %q expands to more than %d calendar entries; use the cron scheduler for schedules this dense", spec, maxCalendarDicts)
+			}
+		}
+		total += len(combos)
+		for _, c := range combos {
+			b.WriteString("\t\t<dict>\n")
+			for i, key := range launchdKeys {
+				if c[i] >= 0 {
+					fmt.Fprintf(&b, "\t\t\t<key>%s</key><integer>%d</integer>\n", key, c[i])
+				}
+			}
+			b.WriteString("\t\t</dict>\n")
+		}
+	}
+	return b.String(), nil
+}
+
+// Install writes the agent plist and loads it, replacing any previous
+// version so frequency changes take effect.
+func (launchdScheduler) Install(frequency, appPath string) error {
+	interval, err := frequencyToCalendarInterval(frequency)
+	if err != nil {
+		return err
+	}
+	path, err := launchdPlistPath()
+	if err != nil {
+		return err
+	}
+	if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+		return err
+	}
+
+	plist := fmt.Sprintf(`<?xml version="1.0" encoding="UTF-8"?>
+<!DOCTYPE plist PUBLIC "-//Apple//DTD PLIST 1.0//EN" "http://www.apple.com/DTDs/PropertyList-1.0.dtd">
+<plist version="1.0">
+<dict>
+	<key>Label</key>
+	<string>%s</string>
+	<key>ProgramArguments</key>
+	<array>
+		<string>%s</string>
+	</array>
+	<key>StartCalendarInterval</key>
+	<array>
+%s	</array>
+</dict>
+</plist>
+`, launchdLabel, appPath, interval)
+
+	// Unload any previous version first; a load over a loaded agent fails.
+	if _, err := os.Stat(path); err == nil {
+		if out, err := exec.Command("launchctl", "unload", path).CombinedOutput(); err != nil {
+			utils.LogMessage("Could not unload previous agent (continuing): %s", strings.TrimSpace(string(out)))
+		}
+	}
+	if err := os.WriteFile(path, []byte(plist), 0o644); err != nil {
+		return err
+	}
+	if out, err := exec.Command("launchctl", "load", path).CombinedOutput(); err != nil {
+		return fmt.Errorf("launchctl load failed: %s: %w", strings.TrimSpace(string(out)), err)
+	}
+	utils.LogMessage("Installed launchd agent %s (%s)", launchdLabel, frequency)
+	return nil
+}
+
+// Remove unloads the agent and deletes the plist. With nothing installed it
+// succeeds quietly.
+func (launchdScheduler) Remove() error {
+	path, err := launchdPlistPath()
+	if err != nil {
+		return err
+	}
+	if _, err := os.Stat(path); os.IsNotExist(err) {
+		return nil
+	}
+	if out, err := exec.Command("launchctl", "unload", path).CombinedOutput(); err != nil {
+		utils.LogMessage("Could not unload agent (continuing): %s", strings.TrimSpace(string(out)))
+	}
+	if err := os.Remove(path); err != nil && !os.IsNotExist(err) {
+		return err
+	}
+	utils.LogMessage("Removed launchd agent %s", launchdLabel)
+	return nil
+}
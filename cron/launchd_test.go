@@ -0,0 +1,60 @@
+package cron
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestFrequencyToCalendarInterval(t *testing.T) {
+	hourly, err := frequencyToCalendarInterval("hourly")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if strings.Count(hourly, "<dict>") != 1 || !strings.Contains(hourly, "<key>Minute</key><integer>0</integer>") {
+		t.Errorf("hourly = %q, want one dict with Minute 0", hourly)
+	}
+
+	custom, err := frequencyToCalendarInterval("30 6 * * 1")
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, want := range []string{
+		"<key>Minute</key><integer>30</integer>",
+		"<key>Hour</key><integer>6</integer>",
+		"<key>Weekday</key><integer>1</integer>",
+	} {
+		if !strings.Contains(custom, want) {
+			t.Errorf("custom spec output %q missing %q", custom, want)
+		}
+	}
+	if strings.Contains(custom, "<key>Day</key>") {
+		t.Errorf("custom spec output %q has a Day key for a wildcard day-of-month", custom)
+	}
+
+	// A spec stepping every 15 minutes expands into one dict per minute value.
+	stepped, err := frequencyToCalendarInterval("*/15 * * * *")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := strings.Count(stepped, "<dict>"); got != 4 {
+		t.Errorf("*/15 spec expanded to %d dicts, want 4", got)
+	}
+
+	// Restricting both day fields yields separate Day and Weekday entries,
+	// matching cron's either-may-match rule.
+	both, err := frequencyToCalendarInterval("0 2 1 * 0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !strings.Contains(both, "<key>Day</key><integer>1</integer>") ||
+		!strings.Contains(both, "<key>Weekday</key><integer>0</integer>") {
+		t.Errorf("dom+dow spec output %q missing separate Day and Weekday dicts", both)
+	}
+
+	if _, err := frequencyToCalendarInterval("0-59 0-23 * * *"); err == nil {
+		t.Error("spec enumerating every minute of every hour should exceed the calendar entry cap")
+	}
+	if _, err := frequencyToCalendarInterval("61 * * * *"); err == nil {
+		t.Error("invalid spec should be rejected")
+	}
+}
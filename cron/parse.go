@@ -0,0 +1,209 @@
+package cron
+
+import (
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// monthNames and dayNames map the three-letter names crontab accepts onto
+// their numeric values.
+var monthNames = map[string]int{
+	"JAN": 1, "FEB": 2, "MAR": 3, "APR": 4, "MAY": 5, "JUN": 6,
+	"JUL": 7, "AUG": 8, "SEP": 9, "OCT": 10, "NOV": 11, "DEC": 12,
+}
+
+var dayNames = map[string]int{
+	"SUN": 0, "MON": 1, "TUE": 2, "WED": 3, "THU": 4, "FRI": 5, "SAT": 6,
+}
+
+// fieldRange describes the legal values of one position in a 5-field spec.
+var fieldRanges = [5]struct {
+	name     string
+	min, max int
+	names    map[string]int
+}{
+	{"minute", 0, 59, nil},
+	{"hour", 0, 23, nil},
+	{"day of month", 1, 31, nil},
+	{"month", 1, 12, monthNames},
+	{"day of week", 0, 6, dayNames},
+}
+
+// schedule holds the allowed values per field of a parsed cron spec.
+type schedule struct {
+	fields [5]map[int]bool
+	// anyDom/anyDow record whether the day fields were "*", for cron's rule
+	// that a restricted day-of-month OR day-of-week may match when both are
+	// restricted.
+	anyDom, anyDow bool
+}
+
+// parseSpec parses a standard 5-field cron spec supporting "*", lists,
+// ranges, and steps (e.g. "*/15 2-4 * * 1,5").
+func parseSpec(spec string) (*schedule, error) {
+	parts := strings.Fields(spec)
+	if len(parts) != 5 {
+		return nil, fmt.Errorf("cron spec %q must have 5 fields, got %d", spec, len(parts))
+	}
+	s := &schedule{}
+	for i, part := range parts {
+		values, err := parseField(part, fieldRanges[i].min, fieldRanges[i].max, fieldRanges[i].names)
+		if err != nil {
+			return nil, fmt.Errorf("invalid %s field %q: %w", fieldRanges[i].name, part, err)
+		}
+		s.fields[i] = values
+	}
+	s.anyDom = parts[2] == "*"
+	s.anyDow = parts[4] == "*"
+	return s, nil
+}
+
+// parseField expands one field into its set of allowed values. names maps
+// crontab's three-letter month/day names onto numbers for the fields that
+// accept them.
+func parseField(field string, min, max int, names map[string]int) (map[int]bool, error) {
+	values := map[int]bool{}
+	for _, item := range strings.Split(field, ",") {
+		step := 1
+		if base, stepStr, ok := strings.Cut(item, "/"); ok {
+			n, err := strconv.Atoi(stepStr)
+			if err != nil || n <= 0 {
+				return nil, fmt.Errorf("bad step %q", stepStr)
+			}
+			step, item = n, base
+		}
+		lo, hi := min, max
+		if item != "*" {
+			if loStr, hiStr, ok := strings.Cut(item, "-"); ok {
+				var err error
+				if lo, err = fieldValue(loStr, names); err != nil {
+					return nil, err
+				}
+				if hi, err = fieldValue(hiStr, names); err != nil {
+					return nil, err
+				}
+			} else {
+				n, err := fieldValue(item, names)
+				if err != nil {
+					return nil, err
+				}
+				lo, hi = n, n
+			}
+		}
+		if lo < min || hi > max || lo > hi {
+			return nil, fmt.Errorf("value out of range %d-%d", min, max)
+		}
+		for v := lo; v <= hi; v += step {
+			values[v] = true
+		}
+	}
+	return values, nil
+}
+
+// fieldValue resolves one token to a number, accepting either digits or a
+// named month/day where the field allows them.
+func fieldValue(s string, names map[string]int) (int, error) {
+	if n, err := strconv.Atoi(s); err == nil {
+		return n, nil
+	}
+	if v, ok := names[strings.ToUpper(s)]; ok {
+		return v, nil
+	}
+	return 0, fmt.Errorf("bad value %q", s)
+}
+
+// specFieldValues expands a 5-field cron spec into the sorted allowed values
+// per field, with nil marking a wildcard ("*") field. The launchd and
+// systemd backends build their calendar expressions from it.
+func specFieldValues(spec string) ([5][]int, error) {
+	var out [5][]int
+	s, err := parseSpec(spec)
+	if err != nil {
+		return out, err
+	}
+	for i, part := range strings.Fields(spec) {
+		if part == "*" {
+			continue
+		}
+		vals := make([]int, 0, len(s.fields[i]))
+		for v := range s.fields[i] {
+			vals = append(vals, v)
+		}
+		sort.Ints(vals)
+		out[i] = vals
+	}
+	return out, nil
+}
+
+// ValidateSpec reports whether spec is a well-formed 5-field cron
+// expression, with a descriptive error naming the offending field. The
+// wizard's custom schedule prompt and frequencyToCronSpec's raw-spec
+// fallback both rely on it to reject garbage before it reaches crontab.
+func ValidateSpec(spec string) error {
+	_, err := parseSpec(spec)
+	return err
+}
+
+// matches reports whether the schedule fires at t (second precision is
+// ignored; cron is minute-granular).
+func (s *schedule) matches(t time.Time) bool {
+	if !s.fields[0][t.Minute()] || !s.fields[1][t.Hour()] || !s.fields[3][int(t.Month())] {
+		return false
+	}
+	domOK := s.fields[2][t.Day()]
+	dowOK := s.fields[4][int(t.Weekday())]
+	// Standard cron: when both day fields are restricted, either may match.
+	if !s.anyDom && !s.anyDow {
+		return domOK || dowOK
+	}
+	return domOK && dowOK
+}
+
+// Next returns the first time strictly after the given instant at which the
+// spec fires.
+func Next(spec string, after time.Time) (time.Time, error) {
+	times, err := NextN(spec, after, 1)
+	if err != nil {
+		return time.Time{}, err
+	}
+	return times[0], nil
+}
+
+// NextN returns the next n fire times strictly after the given instant.
+func NextN(spec string, after time.Time, n int) ([]time.Time, error) {
+	s, err := parseSpec(spec)
+	if err != nil {
+		return nil, err
+	}
+	var times []time.Time
+	t := after.Truncate(time.Minute)
+	// Five years of minutes bounds the search for specs that can never fire
+	// (e.g. Feb 30).
+	for i := 0; i < 5*366*24*60 && len(times) < n; i++ {
+		t = t.Add(time.Minute)
+		if s.matches(t) {
+			times = append(times, t)
+		}
+	}
+	if len(times) < n {
+		return nil, fmt.Errorf("cron spec %q never fires", spec)
+	}
+	return times, nil
+}
+
+// PreviewSchedule resolves a frequency (or a raw 5-field cron spec) to its
+// cron expression and the next n fire times, touching no config or crontab.
+func PreviewSchedule(frequency string, n int) (string, []time.Time, error) {
+	spec, err := frequencyToCronSpec(frequency)
+	if err != nil {
+		return "", nil, err
+	}
+	times, err := NextN(spec, time.Now(), n)
+	if err != nil {
+		return "", nil, err
+	}
+	return spec, times, nil
+}
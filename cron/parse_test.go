@@ -0,0 +1,51 @@
+package cron
+
+import (
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestValidateSpec(t *testing.T) {
+	valid := []string{
+		"0 * * * *",
+		"*/15 2-4 * * 1,5",
+		"0 2 * JAN-MAR SUN",
+		"30 6 1 jan mon-fri",
+	}
+	for _, spec := range valid {
+		if err := ValidateSpec(spec); err != nil {
+			t.Errorf("ValidateSpec(%q) = %v, want nil", spec, err)
+		}
+	}
+
+	invalid := []struct{ spec, wantField string }{
+		{"foo bar baz qux quux", "minute"},
+		{"0 * * *", "5 fields"},
+		{"61 * * * *", "minute"},
+		{"0 25 * * *", "hour"},
+		{"0 2 * BOB *", "month"},
+		{"0 2 * * 9", "day of week"},
+	}
+	for _, c := range invalid {
+		err := ValidateSpec(c.spec)
+		if err == nil {
+			t.Errorf("ValidateSpec(%q) = nil, want error", c.spec)
+			continue
+		}
+		if !strings.Contains(err.Error(), c.wantField) {
+			t.Errorf("ValidateSpec(%q) = %v, want mention of %q", c.spec, err, c.wantField)
+		}
+	}
+}
+
+func TestNextWithNamedFields(t *testing.T) {
+	after := time.Date(2026, time.February, 10, 12, 0, 0, 0, time.UTC)
+	got, err := Next("0 2 * * MON", after)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got.Weekday() != time.Monday || got.Hour() != 2 || got.Minute() != 0 {
+		t.Errorf("expected next Monday 02:00, got %s", got)
+	}
+}
@@ -0,0 +1,79 @@
+package cron
+
+import (
+	"fmt"
+	"os"
+	"os/exec"
+	"runtime"
+	"strings"
+)
+
+// Scheduler installs or removes giterdone's scheduled run. Implementations
+// cover the classic crontab entry and systemd user timers.
+type Scheduler interface {
+	Install(frequency, appPath string) error
+	Remove() error
+}
+
+// SchedulerFor returns the scheduling backend for the configured name.
+// Empty picks the platform default: launchd on macOS (where crontab is
+// deprecated), cron everywhere else.
+func SchedulerFor(name string) (Scheduler, error) {
+	switch name {
+	case "":
+		if runtime.GOOS == "darwin" {
+			return launchdScheduler{}, nil
+		}
+		return cronScheduler{}, nil
+	case "cron":
+		return cronScheduler{}, nil
+	case "systemd":
+		return systemdScheduler{}, nil
+	case "launchd":
+		return launchdScheduler{}, nil
+	default:
+		return nil, fmt.Errorf("unknown scheduler %q (expected cron, systemd, or launchd)", name)
+	}
+}
+
+// JobInstalled reports whether a giterdone scheduled job for the named
+// backend (empty for the platform default) is actually present on the
+// system, for health checks like --status.
+func JobInstalled(name string) bool {
+	if name == "" {
+		if runtime.GOOS == "darwin" {
+			name = "launchd"
+		} else {
+			name = "cron"
+		}
+	}
+	switch name {
+	case "cron":
+		out, _ := exec.Command("crontab", "-l").Output()
+		return existingGiterdoneEntry(strings.Split(string(out), "\n")) != ""
+	case "systemd":
+		return systemctl("is-enabled", "--quiet", systemdUnitName+".timer") == nil
+	case "launchd":
+		path, err := launchdPlistPath()
+		if err != nil {
+			return false
+		}
+		_, err = os.Stat(path)
+		return err == nil
+	default:
+		return false
+	}
+}
+
+// cronScheduler adapts the existing crontab install/remove functions to the
+// Scheduler interface.
+type cronScheduler struct{}
+
+func (cronScheduler) Install(frequency, appPath string) error {
+	return InstallCronJob(frequency, appPath)
+}
+
+func (cronScheduler) Remove() error {
+	_, err := RemoveCronJob()
+	return err
+}
@@ -0,0 +1,171 @@
+package cron
+
+import (
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+
+	"github.com/cdmerchant13/giterdone/utils"
+)
+
+// systemdScheduler schedules runs with a systemd user service and timer,
+// for hosts where cron is absent or unreliable.
+type systemdScheduler struct{}
+
+// systemdUnitName is the base name of the generated service and timer units.
+const systemdUnitName = "giterdone"
+
+// systemdUserDir returns the systemd user unit directory.
+func systemdUserDir() (string, error) {
+	home, err := os.UserHomeDir()
+	if err != nil {
+		return "", fmt.Errorf("could not determine home directory: %w", err)
+	}
+	return filepath.Join(home, ".config", "systemd", "user"), nil
+}
+
+// systemdDays maps cron's weekday numbers (Sunday = 0) onto systemd's
+// abbreviated day names.
+var systemdDays = [7]string{"Sun", "Mon", "Tue", "Wed", "Thu", "Fri", "Sat"}
+
+// frequencyToOnCalendar translates a frequency (or raw cron spec) into
+// systemd OnCalendar expressions matching the times the cron backend uses.
+// Cron fields map almost directly onto OnCalendar components, with each
+// field's allowed values comma-joined. Cron's rule that a restricted
+// day-of-month OR day-of-week may match needs two expressions (systemd ANDs
+// the weekday with the date), so a slice is returned; timers accept one
+// OnCalendar= line per entry.
+func frequencyToOnCalendar(frequency string) ([]string, error) {
+	spec, err := frequencyToCronSpec(frequency)
+	if err != nil {
+		return nil, err
+	}
+	fields, err := specFieldValues(spec)
+	if err != nil {
+		return nil, err
+	}
+	variants := [][5][]int{fields}
+	if fields[2] != nil && fields[4] != nil {
+		domOnly, dowOnly := fields, fields
+		domOnly[4], dowOnly[2] = nil, nil
+		variants = [][5][]int{domOnly, dowOnly}
+	}
+	exprs := make([]string, len(variants))
+	for i, v := range variants {
+		exprs[i] = onCalendarExpr(v)
+	}
+	return exprs, nil
+}
+
+// onCalendarExpr renders one OnCalendar expression from per-field allowed
+// values, nil meaning "any".
+func onCalendarExpr(v [5][]int) string {
+	part := func(vals []int) string {
+		if vals == nil {
+			return "*"
+		}
+		parts := make([]string, len(vals))
+		for i, n := range vals {
+			parts[i] = fmt.Sprintf("%02d", n)
+		}
+		return strings.Join(parts, ",")
+	}
+	expr := fmt.Sprintf("*-%s-%s %s:%s:00", part(v[3]), part(v[2]), part(v[1]), part(v[0]))
+	if v[4] != nil {
+		days := make([]string, len(v[4]))
+		for i, n := range v[4] {
+			days[i] = systemdDays[n]
+		}
+		expr = strings.Join(days, ",") + " " + expr
+	}
+	return expr
+}
+
+// Install writes the service and timer units, reloads the user manager, and
+// enables the timer immediately.
+func (systemdScheduler) Install(frequency, appPath string) error {
+	calendars, err := frequencyToOnCalendar(frequency)
+	if err != nil {
+		return err
+	}
+	dir, err := systemdUserDir()
+	if err != nil {
+		return err
+	}
+	if err := os.MkdirAll(dir, 0o755); err != nil {
+		return err
+	}
+
+	service := fmt.Sprintf(`[Unit]
+Description=Giterdone backup
+
+[Service]
+Type=oneshot
+ExecStart=%s
+`, appPath)
+	timer := fmt.Sprintf(`[Unit]
+Description=Giterdone backup timer
+
+[Timer]
+OnCalendar=%s
+Persistent=true
+
+[Install]
+WantedBy=timers.target
+`, strings.Join(calendars, "\nOnCalendar="))
+
+	if err := os.WriteFile(filepath.Join(dir, systemdUnitName+".service"), []byte(service), 0o644); err != nil {
+		return err
+	}
+	if err := os.WriteFile(filepath.Join(dir, systemdUnitName+".timer"), []byte(timer), 0o644); err != nil {
+		return err
+	}
+
+	if err := systemctl("daemon-reload"); err != nil {
+		return err
+	}
+	if err := systemctl("enable", "--now", systemdUnitName+".timer"); err != nil {
+		return err
+	}
+	utils.LogMessage("Installed systemd timer %s.timer (OnCalendar=%s)", systemdUnitName, strings.Join(calendars, "; "))
+	return nil
+}
+
+// Remove disables the timer and deletes the unit files. With nothing
+// installed it succeeds quietly.
+func (systemdScheduler) Remove() error {
+	dir, err := systemdUserDir()
+	if err != nil {
+		return err
+	}
+	timerPath := filepath.Join(dir, systemdUnitName+".timer")
+	if _, err := os.Stat(timerPath); os.IsNotExist(err) {
+		return nil
+	}
+	// Disabling a timer the manager never loaded fails harmlessly; the unit
+	// files are removed either way.
+	if err := systemctl("disable", "--now", systemdUnitName+".timer"); err != nil {
+		utils.LogMessage("Could not disable timer (continuing): %v", err)
+	}
+	for _, name := range []string{systemdUnitName + ".timer", systemdUnitName + ".service"} {
+		if err := os.Remove(filepath.Join(dir, name)); err != nil && !os.IsNotExist(err) {
+			return err
+		}
+	}
+	if err := systemctl("daemon-reload"); err != nil {
+		return err
+	}
+	utils.LogMessage("Removed systemd timer %s.timer", systemdUnitName)
+	return nil
+}
+
+// systemctl runs a `systemctl --user` subcommand.
+func systemctl(args ...string) error {
+	out, err := exec.Command("systemctl", append([]string{"--user"}, args...)...).CombinedOutput()
+	if err != nil {
+		return fmt.Errorf("systemctl --user %s failed: %s: %w", strings.Join(args, " "), strings.TrimSpace(string(out)), err)
+	}
+	return nil
+}
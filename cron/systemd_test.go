@@ -0,0 +1,40 @@
+package cron
+
+import (
+	"testing"
+)
+
+func TestFrequencyToOnCalendar(t *testing.T) {
+	cases := []struct {
+		frequency string
+		want      []string
+	}{
+		{"hourly", []string{"*-*-* *:00:00"}},
+		{"daily", []string{"*-*-* 02:00:00"}},
+		{"weekly", []string{"Sun *-*-* 02:00:00"}},
+		{"*/15 2-4 * * 1,5", []string{"Mon,Fri *-*-* 02,03,04:00,15,30,45:00"}},
+		// Both day fields restricted: cron fires when either matches, so two
+		// expressions are needed.
+		{"0 2 1 * 0", []string{"*-*-01 02:00:00", "Sun *-*-* 02:00:00"}},
+	}
+	for _, c := range cases {
+		got, err := frequencyToOnCalendar(c.frequency)
+		if err != nil {
+			t.Errorf("frequencyToOnCalendar(%q) = %v, want nil", c.frequency, err)
+			continue
+		}
+		if len(got) != len(c.want) {
+			t.Errorf("frequencyToOnCalendar(%q) = %v, want %v", c.frequency, got, c.want)
+			continue
+		}
+		for i := range got {
+			if got[i] != c.want[i] {
+				t.Errorf("frequencyToOnCalendar(%q)[%d] = %q, want %q", c.frequency, i, got[i], c.want[i])
+			}
+		}
+	}
+
+	if _, err := frequencyToOnCalendar("61 * * * *"); err == nil {
+		t.Error("invalid spec should be rejected")
+	}
+}
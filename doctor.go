@@ -0,0 +1,156 @@
+package main
+
+import (
+	"fmt"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/urfave/cli/v2"
+
+	"github.com/cdmerchant13/giterdone/config"
+	"github.com/cdmerchant13/giterdone/git"
+	"github.com/cdmerchant13/giterdone/utils"
+)
+
+// runDoctor checks the environment and remote connectivity and reports
+// actionable problems.
+func runDoctor(c *cli.Context) error {
+	configDir, err := config.GetConfigDir()
+	if err != nil {
+		return err
+	}
+	if err := utils.InitLogger(configDir, c.Bool("verbose")); err != nil {
+		return err
+	}
+	if err := git.CheckInstalled(); err != nil {
+		return err
+	}
+	fmt.Printf("Git version: %s\n", git.InstalledVersion())
+
+	cfg, err := config.LoadConfig()
+	if err != nil {
+		return fmt.Errorf("could not load config: %w", err)
+	}
+
+	fmt.Printf("Repo URL: %s\n", cfg.SanitizedRepoURL())
+	if err := checkRemoteAuth(cfg); err != nil {
+		return err
+	}
+	fmt.Println("Remote connectivity: OK")
+	return checkPATExpiry(cfg)
+}
+
+// checkRemoteAuth verifies that the configured credentials can reach the
+// remote, translating permission failures into an actionable message about
+// token scopes.
+func checkRemoteAuth(cfg *config.Config) error {
+	err := git.LsRemote(cfg.AuthenticatedRepoURL())
+	if err == nil {
+		return nil
+	}
+	if hint := diagnoseAuthError(cfg, err); hint != "" {
+		return fmt.Errorf("%s\n(underlying error: %v)", hint, err)
+	}
+	return err
+}
+
+// CheckPATExpiry warns (or errors, with PATExpiryFail) when a GitHub
+// fine-grained token expires within the configured number of days. SSH auth
+// and other providers are skipped.
+func checkPATExpiry(cfg *config.Config) error {
+	if cfg.PATExpiryWarnDays <= 0 || cfg.PAT == "" || cfg.EffectiveProvider() != "github" {
+		return nil
+	}
+	repoPath := strings.TrimSuffix(strings.TrimPrefix(cfg.RepoURL, "https://github.com/"), ".git")
+	req, err := http.NewRequest("GET", "https://api.github.com/repos/"+repoPath, nil)
+	if err != nil {
+		return nil
+	}
+	req.Header.Set("Authorization", "Bearer "+cfg.PAT)
+	client := &http.Client{Timeout: 10 * time.Second}
+	resp, err := client.Do(req)
+	if err != nil {
+		utils.LogMessage("Could not check PAT expiry: %v", err)
+		return nil
+	}
+	defer resp.Body.Close()
+
+	// GitHub reports fine-grained token expiry in this header, e.g.
+	// "2026-10-06 18:17:32 UTC". Tokens without expiry omit it.
+	raw := resp.Header.Get("github-authentication-token-expiration")
+	if raw == "" {
+		return nil
+	}
+	expiry, err := time.Parse("2006-01-02 15:04:05 MST", raw)
+	if err != nil {
+		utils.LogMessage("Could not parse PAT expiry %q: %v", raw, err)
+		return nil
+	}
+	remaining := time.Until(expiry)
+	if remaining > time.Duration(cfg.PATExpiryWarnDays)*24*time.Hour {
+		return nil
+	}
+	msg := fmt.Sprintf("PAT expires %s (in %d days) -- generate a new token before backups start failing",
+		expiry.Format("2006-01-02"), int(remaining.Hours()/24))
+	if cfg.PATExpiryFail {
+		return fmt.Errorf("%s", msg)
+	}
+	utils.LogMessage("WARNING: %s", msg)
+	fmt.Println("Warning: " + msg)
+	return nil
+}
+
+// diagnoseAuthError inspects a failed git operation (and, for GitHub, the
+// provider API) to explain missing token scopes or repository access.
+func diagnoseAuthError(cfg *config.Config, gitErr error) string {
+	msg := gitErr.Error()
+	permissionDenied := strings.Contains(msg, "403") ||
+		strings.Contains(msg, "Permission") ||
+		strings.Contains(msg, "Authentication failed") ||
+		strings.Contains(msg, "not authorized")
+	if !permissionDenied {
+		return ""
+	}
+
+	if cfg.PAT != "" && cfg.EffectiveProvider() == "github" {
+		return diagnoseGitHubToken(cfg)
+	}
+	return "The remote rejected the credentials. Check that the token has write access to the repository " +
+		"(for GitHub classic tokens the 'repo' scope, for fine-grained tokens 'contents: write')."
+}
+
+// diagnoseGitHubToken queries the GitHub API to report exactly which scope or
+// repository access the PAT is missing.
+func diagnoseGitHubToken(cfg *config.Config) string {
+	repoPath := strings.TrimSuffix(strings.TrimPrefix(cfg.RepoURL, "https://github.com/"), ".git")
+	req, err := http.NewRequest("GET", "https://api.github.com/repos/"+repoPath, nil)
+	if err != nil {
+		return ""
+	}
+	req.Header.Set("Authorization", "Bearer "+cfg.PAT)
+	client := &http.Client{Timeout: 10 * time.Second}
+	resp, err := client.Do(req)
+	if err != nil {
+		return "Could not reach the GitHub API to check token scopes; the push failure looks like a permission problem. " +
+			"Verify the token has the 'repo' scope (classic) or 'contents: write' (fine-grained)."
+	}
+	defer resp.Body.Close()
+
+	switch resp.StatusCode {
+	case http.StatusUnauthorized:
+		return "The PAT was rejected by GitHub (401). It may be expired or revoked -- generate a new token."
+	case http.StatusNotFound:
+		return "The PAT cannot see the repository at all (404). For fine-grained tokens, grant the token access to this " +
+			"repository; for classic tokens, add the 'repo' scope."
+	case http.StatusOK:
+		scopes := resp.Header.Get("X-OAuth-Scopes")
+		if scopes != "" && !strings.Contains(scopes, "repo") {
+			return fmt.Sprintf("The PAT can read the repository but its scopes (%s) lack 'repo', so pushes will fail. "+
+				"Add the 'repo' scope to the token.", scopes)
+		}
+		return "The PAT can read the repository but the push was denied. For fine-grained tokens, make sure " +
+			"'contents: write' permission is granted."
+	}
+	return ""
+}
@@ -0,0 +1,70 @@
+package git
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+
+	"github.com/cdmerchant13/giterdone/utils"
+)
+
+// Sentinel errors classifying git failures, so callers can branch with
+// errors.Is to make retry and exit-code decisions instead of matching
+// strings.
+var (
+	// ErrAuth marks credential or permission failures; retrying is useless.
+	ErrAuth = errors.New("authentication or permission failure")
+	// ErrNetwork marks transient transport failures worth retrying.
+	ErrNetwork = errors.New("network failure")
+	// ErrNothingToCommit marks a commit with an empty index.
+	ErrNothingToCommit = errors.New("nothing to commit")
+	// ErrNonFastForward marks a push rejected because the remote moved.
+	ErrNonFastForward = errors.New("non-fast-forward push rejected")
+	// ErrNoUpstream marks an operation on a branch with no upstream.
+	ErrNoUpstream = errors.New("no upstream configured")
+)
+
+// classifyGitError wraps a failed git invocation's error with the matching
+// sentinel, classified by parsing the command output.
+func classifyGitError(subcommand, output string, err error) error {
+	// git echoes authenticated URLs back in its diagnostics, so the output
+	// is redacted before it can reach a log or an error message.
+	base := fmt.Errorf("git %s failed: %s: %w", subcommand, utils.Redact(strings.TrimSpace(output)), err)
+	switch {
+	case containsAny(output,
+		"Authentication failed",
+		"Permission denied",
+		"403",
+		"401",
+		"could not read Username",
+		"Invalid username or password"):
+		return fmt.Errorf("%w: %w", ErrAuth, base)
+	case containsAny(output,
+		"Could not resolve host",
+		"Connection reset",
+		"Connection refused",
+		"Connection timed out",
+		"Operation timed out",
+		"unable to access",
+		"early EOF",
+		"The remote end hung up unexpectedly"):
+		return fmt.Errorf("%w: %w", ErrNetwork, base)
+	case containsAny(output, "nothing to commit", "nothing added to commit"):
+		return fmt.Errorf("%w: %w", ErrNothingToCommit, base)
+	case containsAny(output, "non-fast-forward", "fetch first", "rejected"):
+		return fmt.Errorf("%w: %w", ErrNonFastForward, base)
+	case containsAny(output, "no upstream branch", "no tracking information"):
+		return fmt.Errorf("%w: %w", ErrNoUpstream, base)
+	default:
+		return base
+	}
+}
+
+func containsAny(s string, subs ...string) bool {
+	for _, sub := range subs {
+		if strings.Contains(s, sub) {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,638 @@
+package git
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"os/exec"
+	"os/user"
+	"strconv"
+	"strings"
+	"syscall"
+	"time"
+
+	"github.com/cdmerchant13/giterdone/utils"
+)
+
+// runAs, when non-nil, holds the dropped-privilege identity every git child
+// process runs under. Set via ConfigureRunAsUser when running as root.
+var runAs *struct {
+	uid, gid uint32
+	home     string
+}
+
+// ConfigureRunAsUser makes all subsequent git commands run as the named
+// user, with their HOME, so their SSH keys and permissions apply. It must be
+// called as root.
+func ConfigureRunAsUser(username string) error {
+	if os.Geteuid() != 0 {
+		return fmt.Errorf("run_as_user %q is set but giterdone is not running as root", username)
+	}
+	u, err := user.Lookup(username)
+	if err != nil {
+		return fmt.Errorf("could not look up run_as_user %q: %w", username, err)
+	}
+	uid, err := strconv.ParseUint(u.Uid, 10, 32)
+	if err != nil {
+		return err
+	}
+	gid, err := strconv.ParseUint(u.Gid, 10, 32)
+	if err != nil {
+		return err
+	}
+	runAs = &struct {
+		uid, gid uint32
+		home     string
+	}{uint32(uid), uint32(gid), u.HomeDir}
+	utils.LogMessage("Running git operations as %s (uid %d)", username, uid)
+	return nil
+}
+
+// sshKeyPath, when set, pins every git ssh invocation to one identity file.
+var sshKeyPath string
+
+// ConfigureSSHKey makes subsequent git commands authenticate with the key
+// at path via GIT_SSH_COMMAND, so custom-named keys work without touching
+// ~/.ssh/config. IdentitiesOnly stops ssh from trying agent keys first and
+// burning auth attempts.
+func ConfigureSSHKey(path string) {
+	sshKeyPath = path
+}
+
+// sshEnv returns the GIT_SSH_COMMAND entry for the configured key, or nil.
+func sshEnv() []string {
+	if sshKeyPath == "" {
+		return nil
+	}
+	return []string{fmt.Sprintf("GIT_SSH_COMMAND=ssh -i %s -o IdentitiesOnly=yes", sshKeyPath)}
+}
+
+// runGit executes a git command in the given directory and returns its
+// combined output.
+func runGit(dir string, args ...string) (string, error) {
+	cmd := exec.Command("git", args...)
+	if dir != "" {
+		cmd.Dir = dir
+	}
+	if runAs != nil {
+		cmd.SysProcAttr = &syscall.SysProcAttr{
+			Credential: &syscall.Credential{Uid: runAs.uid, Gid: runAs.gid},
+		}
+		cmd.Env = append(os.Environ(), "HOME="+runAs.home)
+	}
+	if env := sshEnv(); env != nil {
+		if cmd.Env == nil {
+			cmd.Env = os.Environ()
+		}
+		cmd.Env = append(cmd.Env, env...)
+	}
+	out, err := cmd.CombinedOutput()
+	if err != nil {
+		return string(out), classifyGitError(args[0], string(out), err)
+	}
+	return string(out), nil
+}
+
+// runGitOutput executes a git command and returns stdout alone, with stderr
+// captured separately for error reporting. Content-bearing commands (like
+// `git show` feeding restored files) must use this so advice messages and
+// warnings on stderr can never be interleaved into the returned bytes.
+func runGitOutput(dir string, args ...string) ([]byte, error) {
+	cmd := exec.Command("git", args...)
+	if dir != "" {
+		cmd.Dir = dir
+	}
+	if runAs != nil {
+		cmd.SysProcAttr = &syscall.SysProcAttr{
+			Credential: &syscall.Credential{Uid: runAs.uid, Gid: runAs.gid},
+		}
+		cmd.Env = append(os.Environ(), "HOME="+runAs.home)
+	}
+	if env := sshEnv(); env != nil {
+		if cmd.Env == nil {
+			cmd.Env = os.Environ()
+		}
+		cmd.Env = append(cmd.Env, env...)
+	}
+	var stderr strings.Builder
+	cmd.Stderr = &stderr
+	out, err := cmd.Output()
+	if err != nil {
+		return nil, classifyGitError(args[0], stderr.String(), err)
+	}
+	return out, nil
+}
+
+// IsGitRepo reports whether dir is the top level of a git repository.
+func IsGitRepo(dir string) bool {
+	_, err := runGit(dir, "rev-parse", "--git-dir")
+	return err == nil
+}
+
+// IsGitDirty reports whether the repo has uncommitted changes.
+func IsGitDirty(dir string) (bool, error) {
+	out, err := runGit(dir, "status", "--porcelain")
+	if err != nil {
+		return false, err
+	}
+	return strings.TrimSpace(out) != "", nil
+}
+
+// CloneRepo clones the remote into dir, retrying transient network errors.
+func CloneRepo(url, dir string) error {
+	utils.LogMessage("Cloning %s", url)
+	return withRetry("clone", func() error {
+		// A half-finished clone from a dropped connection would make the
+		// retry fail on a non-empty directory, so clear it first.
+		os.RemoveAll(dir)
+		_, err := runGit("", "clone", url, dir)
+		return err
+	})
+}
+
+// CloneLocal clones a repo already on the local filesystem into dir, for
+// scratch work trees; no retry, since no network is involved.
+func CloneLocal(src, dir string) error {
+	_, err := runGit("", "clone", "--quiet", src, dir)
+	return err
+}
+
+// SetRemoteOrigin points the repo's origin remote at url.
+func SetRemoteOrigin(dir, url string) error {
+	_, err := runGit(dir, "remote", "set-url", "origin", url)
+	return err
+}
+
+// RemoteOriginURL returns the repo's origin remote URL.
+func RemoteOriginURL(dir string) (string, error) {
+	out, err := runGit(dir, "remote", "get-url", "origin")
+	if err != nil {
+		return "", err
+	}
+	return strings.TrimSpace(out), nil
+}
+
+// addBatchSize caps how many paths go into a single git add invocation, so
+// tens of thousands of files never blow the kernel arg-length limit (E2BIG).
+const addBatchSize = 500
+
+// AddFiles stages the given paths (relative to the repo root), in batches.
+func AddFiles(dir string, files []string) error {
+	for len(files) > 0 {
+		n := len(files)
+		if n > addBatchSize {
+			n = addBatchSize
+		}
+		args := append([]string{"add", "--"}, files[:n]...)
+		if _, err := runGit(dir, args...); err != nil {
+			return err
+		}
+		files = files[n:]
+	}
+	return nil
+}
+
+// Commit records staged changes with the given message. A repo with nothing
+// staged is not an error.
+func Commit(dir, message string) error {
+	_, err := runGit(dir, commitArgs("commit", "-m", message)...)
+	if err != nil {
+		if errors.Is(err, ErrNothingToCommit) {
+			utils.LogMessage("Nothing to commit")
+			return nil
+		}
+		return err
+	}
+	return nil
+}
+
+// CommitAllowEmpty records a commit even when nothing is staged, used for
+// heartbeat commits that prove a scheduled run happened.
+func CommitAllowEmpty(dir, message string) error {
+	_, err := runGit(dir, commitArgs("commit", "--allow-empty", "-m", message)...)
+	return err
+}
+
+// commitArgs decorates a commit invocation with the configured identity and
+// GPG signing flag. Identity goes in as -c overrides so commits succeed on
+// boxes with no global user.name/user.email at all.
+func commitArgs(args ...string) []string {
+	if signingKey != "" {
+		args = append(args, "--gpg-sign="+signingKey)
+	}
+	var pre []string
+	if authorName != "" {
+		pre = append(pre, "-c", "user.name="+authorName)
+	}
+	if authorEmail != "" {
+		pre = append(pre, "-c", "user.email="+authorEmail)
+	}
+	return append(pre, args...)
+}
+
+// authorName and authorEmail, when non-empty, override the git identity for
+// backup commits.
+var authorName, authorEmail string
+
+// ConfigureCommitIdentity sets the author/committer identity for this run's
+// commits.
+func ConfigureCommitIdentity(name, email string) {
+	authorName, authorEmail = name, email
+}
+
+// signingKey, when non-empty, makes every commit GPG-signed with this key.
+var signingKey string
+
+// ConfigureSigningKey sets the key commits are signed with for this run.
+func ConfigureSigningKey(key string) {
+	signingKey = key
+}
+
+// HasSecretKey reports whether the key is present in the GPG secret
+// keyring.
+func HasSecretKey(key string) bool {
+	return exec.Command("gpg", "--batch", "--list-secret-keys", key).Run() == nil
+}
+
+// VerifySigningKey checks that the key exists in the secret keyring and
+// that the gpg agent can sign without prompting, so a scheduled run fails
+// with a clear message instead of hanging on a passphrase prompt.
+func VerifySigningKey(key string) error {
+	if out, err := exec.Command("gpg", "--batch", "--list-secret-keys", key).CombinedOutput(); err != nil {
+		return fmt.Errorf("signing key %s not found in the GPG secret keyring: %s", key, strings.TrimSpace(string(out)))
+	}
+	cmd := exec.Command("gpg", "--batch", "--pinentry-mode", "error", "-u", key, "--sign", "--output", os.DevNull)
+	cmd.Stdin = strings.NewReader("giterdone signing check")
+	if out, err := cmd.CombinedOutput(); err != nil {
+		return fmt.Errorf("gpg cannot sign non-interactively with key %s (is the agent running and the passphrase cached?): %s",
+			key, strings.TrimSpace(string(out)))
+	}
+	return nil
+}
+
+// pushRateLimitKBps, when non-zero, throttles pushes via trickle.
+var pushRateLimitKBps int
+
+// targetBranch, when non-empty, names the branch pushes target instead of
+// whatever HEAD happens to be.
+var targetBranch string
+
+// ConfigureTargetBranch sets the branch pushes target for this run. Callers
+// are expected to have checked the branch out first.
+func ConfigureTargetBranch(branch string) {
+	targetBranch = branch
+}
+
+// pushRef returns the refspec pushes use: the configured branch, or HEAD so
+// empty bare remotes (with no default branch yet) work on the first push.
+func pushRef() string {
+	if targetBranch != "" {
+		return targetBranch
+	}
+	return "HEAD"
+}
+
+// ConfigurePushRateLimit sets an approximate upload rate cap for pushes.
+// Throttling uses trickle when installed; otherwise a warning is logged and
+// pushes run unthrottled.
+func ConfigurePushRateLimit(kbps int) {
+	pushRateLimitKBps = kbps
+	if kbps > 0 {
+		if _, err := exec.LookPath("trickle"); err != nil {
+			utils.LogMessage("Warning: push_rate_limit_kbps is set but trickle is not installed; pushes will not be throttled")
+			pushRateLimitKBps = 0
+		}
+	}
+}
+
+// Push pushes the current branch to origin, setting the upstream so empty
+// bare remotes (which have no default branch yet) work on the first push.
+// Slow stalled transfers abort predictably via git's low-speed limits, and
+// transient network errors are retried with backoff.
+func Push(dir string) error {
+	utils.LogMessage("Pushing to remote")
+	return withRetry("push", func() error { return pushOnce(dir) })
+}
+
+func pushOnce(dir string) error {
+	args := []string{"git", "push", "-u", "origin", pushRef()}
+	if pushRateLimitKBps > 0 {
+		args = append([]string{"trickle", "-s", "-u", strconv.Itoa(pushRateLimitKBps)}, args...)
+	}
+	cmd := exec.Command(args[0], args[1:]...)
+	cmd.Dir = dir
+	// Abort the push if the transfer rate drops below 1KB/s for 60s rather
+	// than hanging indefinitely on a dead link.
+	cmd.Env = append(os.Environ(), "GIT_HTTP_LOW_SPEED_LIMIT=1000", "GIT_HTTP_LOW_SPEED_TIME=60")
+	cmd.Env = append(cmd.Env, sshEnv()...)
+	if runAs != nil {
+		cmd.SysProcAttr = &syscall.SysProcAttr{
+			Credential: &syscall.Credential{Uid: runAs.uid, Gid: runAs.gid},
+		}
+		cmd.Env = append(cmd.Env, "HOME="+runAs.home)
+	}
+	out, err := cmd.CombinedOutput()
+	if err != nil {
+		return classifyGitError("push", string(out), err)
+	}
+	return nil
+}
+
+// ResetSoft moves HEAD back to ref keeping the index and work tree, used to
+// squash the commits after ref into one.
+func ResetSoft(dir, ref string) error {
+	_, err := runGit(dir, "reset", "--soft", ref)
+	return err
+}
+
+// LastCommitTime returns the commit date of HEAD, or "" for a repo with no
+// commits yet.
+func LastCommitTime(dir string) string {
+	out, err := runGit(dir, "log", "-1", "--format=%cd", "--date=format:%Y-%m-%d %H:%M:%S")
+	if err != nil {
+		return ""
+	}
+	return strings.TrimSpace(out)
+}
+
+// LogEntry is one commit as reported by LogEntries.
+type LogEntry struct {
+	Hash         string
+	Date         string
+	Subject      string
+	FilesChanged int
+}
+
+// LogEntries returns the last n commits, newest first, with the number of
+// files each one changed.
+func LogEntries(dir string, n int) ([]LogEntry, error) {
+	out, err := runGit(dir, "log", "-n", strconv.Itoa(n), "--date=short",
+		"--pretty=format:%h%x09%ad%x09%s", "--shortstat")
+	if err != nil {
+		return nil, err
+	}
+	var entries []LogEntry
+	for _, line := range strings.Split(out, "\n") {
+		line = strings.TrimSpace(line)
+		if line == "" {
+			continue
+		}
+		if parts := strings.SplitN(line, "\t", 3); len(parts) == 3 {
+			entries = append(entries, LogEntry{Hash: parts[0], Date: parts[1], Subject: parts[2]})
+			continue
+		}
+		// A shortstat line like "3 files changed, 10 insertions(+)"
+		// belongs to the commit header above it.
+		if len(entries) > 0 && strings.Contains(line, "file") {
+			fmt.Sscanf(line, "%d", &entries[len(entries)-1].FilesChanged)
+		}
+	}
+	return entries, nil
+}
+
+// LogSubjects returns the subject lines of the last n commits, newest first.
+func LogSubjects(dir string, n int) ([]string, error) {
+	out, err := runGit(dir, "log", "--format=%s", "-n", strconv.Itoa(n))
+	if err != nil {
+		return nil, err
+	}
+	var subjects []string
+	for _, line := range strings.Split(out, "\n") {
+		if line = strings.TrimSpace(line); line != "" {
+			subjects = append(subjects, line)
+		}
+	}
+	return subjects, nil
+}
+
+// CommitCount returns the number of commits reachable from HEAD, 0 for a
+// repo with no commits yet.
+func CommitCount(dir string) int {
+	out, err := runGit(dir, "rev-list", "--count", "HEAD")
+	if err != nil {
+		return 0
+	}
+	n, _ := strconv.Atoi(strings.TrimSpace(out))
+	return n
+}
+
+// PushForceWithLease force-pushes the current branch, refusing to clobber
+// remote commits we have not seen. Only history-rewriting features use it.
+func PushForceWithLease(dir string) error {
+	utils.LogMessage("Force-pushing rewritten history (with lease)")
+	_, err := runGit(dir, "push", "--force-with-lease", "-u", "origin", pushRef())
+	return err
+}
+
+// PushForce force-pushes unconditionally, discarding whatever the remote
+// holds. Only the explicit history-scrubbing command uses it; everything
+// else goes through PushForceWithLease at most.
+func PushForce(dir string) error {
+	utils.LogMessage("Force-pushing rewritten history (unconditional)")
+	_, err := runGit(dir, "push", "--force", "-u", "origin", pushRef())
+	return err
+}
+
+// HasFilterRepo reports whether the git-filter-repo extension is installed.
+func HasFilterRepo() bool {
+	err := exec.Command("git", "filter-repo", "--version").Run()
+	return err == nil
+}
+
+// FilterRepoRemovePath rewrites the entire history to drop the given
+// repo-relative path, preferring git-filter-repo and falling back to the
+// slower filter-branch when it is not installed.
+func FilterRepoRemovePath(dir, rel string) error {
+	if HasFilterRepo() {
+		_, err := runGit(dir, "filter-repo", "--invert-paths", "--path", rel, "--force")
+		return err
+	}
+	utils.LogMessage("git-filter-repo is not installed; falling back to git filter-branch (slower). Install it with: pip install git-filter-repo")
+	_, err := runGit(dir, "filter-branch", "--force", "--index-filter",
+		"git rm --cached --ignore-unmatch '"+strings.ReplaceAll(rel, "'", `'\''`)+"'",
+		"--prune-empty", "--", "--all")
+	return err
+}
+
+// InitRepo initializes a fresh repository in dir pointing at url, used when
+// cloning fails because the remote is an empty bare repo.
+func InitRepo(dir, url string) error {
+	if err := os.MkdirAll(dir, 0o755); err != nil {
+		return err
+	}
+	if _, err := runGit(dir, "init"); err != nil {
+		return err
+	}
+	_, err := runGit(dir, "remote", "add", "origin", url)
+	return err
+}
+
+// AddRemote registers an additional named remote.
+func AddRemote(dir, name, url string) error {
+	_, err := runGit(dir, "remote", "add", name, url)
+	return err
+}
+
+// PushAll pushes every branch and tag to the named remote.
+func PushAll(dir, remote string) error {
+	if _, err := runGit(dir, "push", remote, "--all"); err != nil {
+		return err
+	}
+	_, err := runGit(dir, "push", remote, "--tags")
+	return err
+}
+
+// LsRemote verifies the remote is reachable and lists its refs.
+func LsRemote(url string) error {
+	_, err := runGit("", "ls-remote", url)
+	return err
+}
+
+// Fetch updates the repo's view of origin without touching the work tree.
+func Fetch(dir string) error {
+	return withRetry("fetch", func() error {
+		_, err := runGit(dir, "fetch", "origin")
+		return err
+	})
+}
+
+// FetchURL fetches from an explicit URL rather than the configured origin,
+// setting the repo's FETCH_HEAD without touching the work tree.
+func FetchURL(dir, url string) error {
+	return withRetry("fetch", func() error {
+		_, err := runGit(dir, "fetch", url)
+		return err
+	})
+}
+
+// RebaseOntoRemote fetches and rebases the current branch onto its origin
+// counterpart, so a push fast-forwards even when the remote gained commits
+// (e.g. a file edited through the web UI). A branch with no remote
+// counterpart yet is left alone, and a conflicted rebase is aborted so the
+// repo is never left mid-rebase for the next scheduled run to trip over.
+func RebaseOntoRemote(dir string) error {
+	if err := Fetch(dir); err != nil {
+		utils.LogMessage("Could not fetch before rebase (%v); continuing without rebasing", err)
+		return nil
+	}
+	out, err := runGit(dir, "rev-parse", "--abbrev-ref", "HEAD")
+	if err != nil {
+		// A fresh clone of an empty remote has an unborn HEAD; there is
+		// nothing to rebase and the first push will create the branch.
+		utils.LogMessage("No commits yet (%v); skipping rebase", err)
+		return nil
+	}
+	branch := strings.TrimSpace(out)
+	if _, err := runGit(dir, "rev-parse", "--verify", "origin/"+branch); err != nil {
+		// No remote counterpart yet; the first push will create it.
+		return nil
+	}
+	if _, err := runGit(dir, "rebase", "origin/"+branch); err != nil {
+		if _, abortErr := runGit(dir, "rebase", "--abort"); abortErr != nil {
+			utils.LogMessage("Could not abort failed rebase: %v", abortErr)
+		}
+		return fmt.Errorf("local backup history conflicts with origin/%s and the rebase was aborted -- "+
+			"inspect the repo with 'giterdone git log' or set pull_before_push to false: %w", branch, err)
+	}
+	return nil
+}
+
+// DiffCachedStat returns a diffstat of the staged changes against ref.
+func DiffCachedStat(dir, ref string) (string, error) {
+	return runGit(dir, "diff", "--cached", "--stat", ref)
+}
+
+// Reset unstages everything, leaving the work tree alone.
+func Reset(dir string) error {
+	_, err := runGit(dir, "reset", "--quiet")
+	return err
+}
+
+// AddAll stages every change in the work tree.
+func AddAll(dir string) error {
+	_, err := runGit(dir, "add", "-A")
+	return err
+}
+
+// Pull brings the local backup repo up to date with origin.
+func Pull(dir string) error {
+	_, err := runGit(dir, "pull", "--ff-only", "origin")
+	return err
+}
+
+// LsFiles returns every tracked path in the repo.
+func LsFiles(dir string) ([]string, error) {
+	out, err := runGit(dir, "ls-files")
+	if err != nil {
+		return nil, err
+	}
+	var files []string
+	for _, line := range strings.Split(out, "\n") {
+		if line = strings.TrimSpace(line); line != "" {
+			files = append(files, line)
+		}
+	}
+	return files, nil
+}
+
+// ShowFile returns the contents of path as of the given commit.
+func ShowFile(dir, commit, path string) ([]byte, error) {
+	return runGitOutput(dir, "show", commit+":"+path)
+}
+
+// CheckoutBranch switches to the named branch, creating it from the current
+// HEAD if it does not exist yet.
+func CheckoutBranch(dir, branch string) error {
+	if _, err := runGit(dir, "rev-parse", "--verify", "refs/heads/"+branch); err == nil {
+		_, err = runGit(dir, "checkout", branch)
+		return err
+	}
+	utils.LogMessage("Creating branch %s", branch)
+	_, err := runGit(dir, "checkout", "-b", branch)
+	return err
+}
+
+// SanitizeBranchName replaces characters git refuses in ref names so
+// arbitrary hostnames can be used as branches.
+func SanitizeBranchName(name string) string {
+	sanitized := strings.Map(func(r rune) rune {
+		switch {
+		case r >= 'a' && r <= 'z', r >= 'A' && r <= 'Z', r >= '0' && r <= '9':
+			return r
+		case r == '-' || r == '.' || r == '_' || r == '/':
+			return r
+		default:
+			return '-'
+		}
+	}, name)
+	sanitized = strings.Trim(sanitized, "-./")
+	if sanitized == "" {
+		sanitized = "host"
+	}
+	return sanitized
+}
+
+// Head returns the current HEAD commit hash, or "" for a repo with no
+// commits yet.
+func Head(dir string) string {
+	out, err := runGit(dir, "rev-parse", "HEAD")
+	if err != nil {
+		return ""
+	}
+	return strings.TrimSpace(out)
+}
+
+// IsRemoteReachable reports whether the remote answers a ls-remote within a
+// short timeout, used to skip runs cleanly while offline.
+func IsRemoteReachable(url string) bool {
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+	return exec.CommandContext(ctx, "git", "ls-remote", "--exit-code", url, "HEAD").Run() == nil
+}
+
+// Stash saves uncommitted changes away, including untracked files.
+func Stash(dir string) error {
+	_, err := runGit(dir, "stash", "push", "--include-untracked", "-m", "giterdone: pre-run stash")
+	return err
+}
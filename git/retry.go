@@ -0,0 +1,46 @@
+package git
+
+import (
+	"errors"
+	"math/rand"
+	"time"
+
+	"github.com/cdmerchant13/giterdone/utils"
+)
+
+// Retry policy for network-facing git calls (clone, fetch, push). A cron
+// run that hits a transient blip would otherwise miss a whole backup tick,
+// so a few short retries are on by default.
+var (
+	retryAttempts  = 3
+	retryBaseDelay = 2 * time.Second
+)
+
+// ConfigureRetry overrides the retry policy for this run; zero values keep
+// the defaults.
+func ConfigureRetry(attempts, baseDelaySeconds int) {
+	if attempts > 0 {
+		retryAttempts = attempts
+	}
+	if baseDelaySeconds > 0 {
+		retryBaseDelay = time.Duration(baseDelaySeconds) * time.Second
+	}
+}
+
+// withRetry runs op up to retryAttempts times with exponential backoff and
+// jitter, but only when the failure classifies as ErrNetwork -- auth
+// failures and everything else fail fast, since retrying them is useless.
+func withRetry(name string, op func() error) error {
+	delay := retryBaseDelay
+	for attempt := 1; ; attempt++ {
+		err := op()
+		if err == nil || !errors.Is(err, ErrNetwork) || attempt >= retryAttempts {
+			return err
+		}
+		wait := delay + time.Duration(rand.Int63n(int64(delay/2)+1))
+		utils.LogMessage("Warning: git %s failed with a network error (attempt %d/%d), retrying in %s",
+			name, attempt, retryAttempts, wait.Round(time.Second))
+		time.Sleep(wait)
+		delay *= 2
+	}
+}
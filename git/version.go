@@ -0,0 +1,80 @@
+package git
+
+import (
+	"fmt"
+	"os/exec"
+	"strconv"
+	"strings"
+	"sync"
+)
+
+// Minimum git version giterdone supports; everything it shells out for
+// (force-with-lease, -C, etc.) is available from 2.0 on.
+const (
+	minGitMajor = 2
+	minGitMinor = 0
+)
+
+// gitCheck caches the PATH/version probe so every command in a run doesn't
+// re-exec `git version`.
+var gitCheck struct {
+	once    sync.Once
+	version string
+	err     error
+}
+
+// CheckInstalled verifies, once per process, that git is on PATH and meets
+// the minimum supported version. Everything giterdone does shells out to
+// git, so a missing binary is caught here with an actionable message
+// instead of surfacing as a cryptic exec error mid-backup.
+func CheckInstalled() error {
+	gitCheck.once.Do(func() {
+		if _, err := exec.LookPath("git"); err != nil {
+			gitCheck.err = fmt.Errorf("git is not installed or not on PATH -- install it first " +
+				"(e.g. 'apt install git', 'dnf install git' or 'brew install git')")
+			return
+		}
+		out, err := exec.Command("git", "version").Output()
+		if err != nil {
+			gitCheck.err = fmt.Errorf("could not run 'git version': %w", err)
+			return
+		}
+		version := strings.TrimSpace(strings.TrimPrefix(strings.TrimSpace(string(out)), "git version "))
+		gitCheck.version = version
+		major, minor, ok := parseVersion(version)
+		if !ok {
+			// An unparseable version is not worth refusing to run over.
+			return
+		}
+		if major < minGitMajor || (major == minGitMajor && minor < minGitMinor) {
+			gitCheck.err = fmt.Errorf("git %s is too old (need at least %d.%d) -- upgrade via your package manager",
+				version, minGitMajor, minGitMinor)
+		}
+	})
+	return gitCheck.err
+}
+
+// InstalledVersion returns the detected git version ("2.39.2"), or "" when
+// git is missing entirely.
+func InstalledVersion() string {
+	CheckInstalled()
+	return gitCheck.version
+}
+
+// parseVersion extracts major and minor from a `git version` string such as
+// "2.39.2" or "2.43.0.windows.1".
+func parseVersion(version string) (major, minor int, ok bool) {
+	parts := strings.Split(version, ".")
+	if len(parts) < 2 {
+		return 0, 0, false
+	}
+	major, err := strconv.Atoi(parts[0])
+	if err != nil {
+		return 0, 0, false
+	}
+	minor, err = strconv.Atoi(parts[1])
+	if err != nil {
+		return 0, 0, false
+	}
+	return major, minor, true
+}
@@ -0,0 +1,17 @@
+module github.com/cdmerchant13/giterdone
+
+go 1.21.6
+
+require (
+	github.com/manifoldco/promptui v0.9.0
+	github.com/urfave/cli/v2 v2.27.1
+	golang.org/x/crypto v0.18.0
+)
+
+require (
+	github.com/chzyer/readline v0.0.0-20180603132655-2972be24d48e // indirect
+	github.com/cpuguy83/go-md2man/v2 v2.0.2 // indirect
+	github.com/russross/blackfriday/v2 v2.1.0 // indirect
+	github.com/xrash/smetrics v0.0.0-20201216005158-039620a65673 // indirect
+	golang.org/x/sys v0.16.0 // indirect
+)
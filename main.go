@@ -0,0 +1,1839 @@
+package main
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"os/exec"
+	"os/user"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/manifoldco/promptui"
+	"github.com/urfave/cli/v2"
+
+	"github.com/cdmerchant13/giterdone/config"
+	"github.com/cdmerchant13/giterdone/cron"
+	"github.com/cdmerchant13/giterdone/git"
+	"github.com/cdmerchant13/giterdone/manifest"
+	"github.com/cdmerchant13/giterdone/scanner"
+	"github.com/cdmerchant13/giterdone/utils"
+)
+
+func main() {
+	app := &cli.App{
+		Name:    "giterdone",
+		Usage:   "back up config files and directories to a git repository on a schedule",
+		Version: version,
+		Before: func(c *cli.Context) error {
+			if path := c.String("config"); path != "" {
+				config.SetConfigPathOverride(path)
+			}
+			return nil
+		},
+		Flags: []cli.Flag{
+			&cli.StringFlag{
+				Name:    "config",
+				Usage:   "use this config file instead of the default location",
+				EnvVars: []string{"GITERDONE_CONFIG"},
+			},
+			&cli.BoolFlag{Name: "init", Usage: "re-run the setup wizard"},
+			&cli.BoolFlag{Name: "init-if-missing", Usage: "run the setup wizard only when no config exists, then exit"},
+			&cli.BoolFlag{Name: "status", Usage: "report repo sizes and growth, then exit"},
+			&cli.BoolFlag{Name: "list", Usage: "list recent backup commits, then exit"},
+			&cli.IntFlag{Name: "list-count", Value: 10, Usage: "number of commits --list shows"},
+			&cli.BoolFlag{Name: "print-config-path", Usage: "print the resolved config file path and exit"},
+			&cli.BoolFlag{Name: "print-paths", Usage: "print the resolved config, log, state, and repo paths and exit"},
+			&cli.StringFlag{
+				Name:  "dry-run",
+				Usage: "dry-run level: 'offline' skips all git operations, 'remote' validates connectivity with ls-remote and diffs against the remote HEAD",
+			},
+			&cli.StringFlag{
+				Name:  "dry-run-report",
+				Usage: "write the dry-run result as a shareable report to this file (JSON for .json paths, markdown otherwise); implies --dry-run offline",
+			},
+			&cli.BoolFlag{Name: "stdin-paths", Usage: "read newline-delimited include paths from stdin for this run"},
+			&cli.BoolFlag{Name: "verbose", Aliases: []string{"v"}, Usage: "print log output to stdout"},
+			&cli.BoolFlag{Name: "assume-yes", Aliases: []string{"y"}, Usage: "auto-accept all confirmation prompts"},
+			&cli.BoolFlag{Name: "only-if-online", Usage: "skip the run cleanly when the remote is unreachable"},
+			&cli.BoolFlag{Name: "export-repo-url", Usage: "print the configured repo URL with credentials stripped and exit"},
+			&cli.StringFlag{Name: "profile", Usage: "use the named profile's config (config.<name>.json), or 'all' to run every profile"},
+			&cli.BoolFlag{Name: "prefer-https", Usage: "use HTTPS+PAT auth for this run regardless of the configured auth method"},
+			&cli.BoolFlag{Name: "prefer-ssh", Usage: "use SSH auth for this run regardless of the configured auth method"},
+			&cli.BoolFlag{Name: "force-push", Usage: "push this run even if push_every_minutes would defer it"},
+		},
+		Action: run,
+		Commands: []*cli.Command{
+			{
+				Name:   "backup-now",
+				Usage:  "run a backup immediately and report the result in detail",
+				Action: runBackupNow,
+			},
+			{
+				Name:   "doctor",
+				Usage:  "check environment, credentials, and remote connectivity",
+				Action: runDoctor,
+			},
+			{
+				Name:   "list-excluded",
+				Usage:  "scan and print every excluded file with the reason it was skipped",
+				Action: runListExcluded,
+			},
+			{
+				Name:  "config",
+				Usage: "inspect the giterdone configuration",
+				Subcommands: []*cli.Command{
+					{
+						Name:  "show",
+						Usage: "print the fully-resolved effective config with secrets redacted",
+						Flags: []cli.Flag{
+							&cli.BoolFlag{Name: "json", Usage: "output as JSON"},
+						},
+						Action: runConfigShow,
+					},
+				},
+			},
+			{
+				Name:  "manifest",
+				Usage: "inspect and repair the backup manifest",
+				Subcommands: []*cli.Command{
+					{
+						Name:  "verify",
+						Usage: "cross-check the manifest against tracked files",
+						Flags: []cli.Flag{
+							&cli.BoolFlag{Name: "fix", Usage: "reconcile the manifest to match the repo"},
+						},
+						Action: runManifestVerify,
+					},
+				},
+			},
+			{
+				Name:  "restore",
+				Usage: "copy backed-up files from the repo back to their original locations",
+				Flags: []cli.Flag{
+					&cli.StringSliceFlag{Name: "only", Usage: "restore only this path (repeatable)"},
+					&cli.StringFlag{Name: "from", Usage: "restore file contents as of this commit"},
+					&cli.BoolFlag{Name: "dry-run", Usage: "print what would be written without touching any files"},
+					&cli.BoolFlag{Name: "assume-yes", Aliases: []string{"y"}, Usage: "overwrite existing files without prompting"},
+				},
+				Action: runRestore,
+			},
+			{
+				Name:      "what-if",
+				Usage:     "preview the cron spec and next fire times for a frequency without changing anything",
+				ArgsUsage: "<frequency>",
+				Action:    runWhatIf,
+			},
+			{
+				Name:            "git",
+				Usage:           "run an arbitrary git command against the backup repo",
+				ArgsUsage:       "-- <args...>",
+				SkipFlagParsing: true,
+				Action:          runGitPassthrough,
+			},
+			{
+				Name:      "rewrite-history-remove",
+				Usage:     "purge a file from ALL history and force-push (recovery for committed secrets)",
+				ArgsUsage: "<path>",
+				Flags: []cli.Flag{
+					&cli.BoolFlag{Name: "force", Usage: "confirm that rewriting shared history is intended"},
+				},
+				Action: runRewriteHistoryRemove,
+			},
+			{
+				Name:  "uninstall",
+				Usage: "remove the installed cron job; with --purge also delete all local giterdone data",
+				Flags: []cli.Flag{
+					&cli.BoolFlag{Name: "purge", Usage: "also delete the config directory (config, state, logs, repo clone)"},
+					&cli.BoolFlag{Name: "assume-yes", Aliases: []string{"y"}, Usage: "skip the purge confirmation prompt"},
+				},
+				Action: runUninstall,
+			},
+			{
+				Name:      "adopt",
+				Usage:     "take over an existing git repo (URL or local clone) as the backup repo",
+				ArgsUsage: "<path-or-url>",
+				Action:    runAdopt,
+			},
+			{
+				Name:      "migrate-remote",
+				Usage:     "repoint the backup at a new remote and push existing history to it",
+				ArgsUsage: "<new-url>",
+				Flags: []cli.Flag{
+					&cli.BoolFlag{Name: "keep-old-as-mirror", Usage: "keep the previous remote configured as 'mirror'"},
+				},
+				Action: runMigrateRemote,
+			},
+		},
+	}
+	if err := app.Run(os.Args); err != nil {
+		fmt.Fprintln(os.Stderr, "Error:", err)
+		os.Exit(1)
+	}
+}
+
+// assumeYes is set by --assume-yes and makes every confirmation prompt
+// auto-accept, so giterdone can be driven from provisioning scripts.
+var assumeYes bool
+
+// confirm asks the user a yes/no question. With --assume-yes it accepts
+// without prompting; the defaulted answer is logged.
+func confirm(label string) (bool, error) {
+	if assumeYes {
+		utils.LogMessage("Auto-accepting prompt (--assume-yes): %s", label)
+		return true, nil
+	}
+	if !utils.IsInteractive() {
+		return false, fmt.Errorf("refusing to prompt %q: no terminal attached (pass --assume-yes for non-interactive use)", label)
+	}
+	prompt := promptui.Prompt{Label: label, IsConfirm: true}
+	_, err := prompt.Run()
+	if err == promptui.ErrAbort {
+		return false, nil
+	}
+	if err != nil {
+		return false, err
+	}
+	return true, nil
+}
+
+// forcePush is set by --force-push and overrides the push_every_minutes
+// deferral for one run.
+var forcePush bool
+
+// applyRunConfig pushes a loaded config's per-run knobs into the package-level
+// state in utils and git. Values left unset in the config are reset rather
+// than skipped, so `--profile all` runs never leak one profile's branch, SSH
+// key, or signing key into the next.
+func applyRunConfig(cfg *config.Config) error {
+	if cfg.RunAsUser != "" {
+		if err := git.ConfigureRunAsUser(cfg.RunAsUser); err != nil {
+			return err
+		}
+	}
+	if err := utils.SetLogFormat(cfg.LogFormat); err != nil {
+		return err
+	}
+	utils.RegisterSecret(cfg.PAT)
+	utils.SetLogRotation(cfg.LogMaxSizeBytes, cfg.LogMaxBackups)
+	git.ConfigurePushRateLimit(cfg.PushRateLimitKBps)
+	git.ConfigureRetry(cfg.NetworkRetries, cfg.NetworkRetryBaseSeconds)
+	git.ConfigureTargetBranch("")
+	git.ConfigureSSHKey("")
+	if cfg.SSHKeyPath != "" {
+		git.ConfigureSSHKey(config.ExpandUserPath(cfg.SSHKeyPath))
+	}
+	git.ConfigureCommitIdentity(cfg.CommitAuthorName, cfg.CommitAuthorEmail)
+	git.ConfigureSigningKey("")
+	if cfg.SigningKey != "" {
+		if err := git.VerifySigningKey(cfg.SigningKey); err != nil {
+			return err
+		}
+		git.ConfigureSigningKey(cfg.SigningKey)
+	}
+	return nil
+}
+
+func run(c *cli.Context) error {
+	assumeYes = c.Bool("assume-yes")
+	forcePush = c.Bool("force-push")
+	if c.Bool("print-config-path") || c.Bool("print-paths") {
+		return printPaths(c.Bool("print-paths"))
+	}
+
+	configDir, err := config.GetConfigDir()
+	if err != nil {
+		return err
+	}
+	if err := utils.InitLogger(configDir, c.Bool("verbose")); err != nil {
+		return err
+	}
+	// Everything below shells out to git; fail up front with install
+	// instructions rather than mid-run with a cryptic exec error.
+	if err := git.CheckInstalled(); err != nil {
+		return err
+	}
+
+	dryRun := c.String("dry-run")
+	switch dryRun {
+	case "", "offline", "remote":
+	default:
+		return fmt.Errorf("invalid --dry-run level %q (expected 'offline' or 'remote')", dryRun)
+	}
+	dryRunReportPath = c.String("dry-run-report")
+	if dryRunReportPath != "" && dryRun == "" {
+		dryRun = "offline"
+	}
+
+	switch profile := c.String("profile"); profile {
+	case "all":
+		return runAllProfiles(dryRun, c.Bool("only-if-online"))
+	case "":
+	default:
+		config.SetActiveProfile(profile)
+	}
+
+	cfg, err := config.LoadConfig()
+	if c.Bool("init-if-missing") {
+		// Idempotent bootstrap for provisioning scripts: only the very
+		// first run prompts; every later run is a silent no-op.
+		if err == nil {
+			utils.LogMessage("Config already exists; skipping setup (--init-if-missing)")
+			return nil
+		}
+		if !os.IsNotExist(err) {
+			return err
+		}
+		if !utils.IsInteractive() {
+			return fmt.Errorf("no config found and no terminal attached: setup requires an interactive terminal")
+		}
+		_, err = runSetupWizard()
+		return err
+	}
+	if os.IsNotExist(err) || c.Bool("init") {
+		if !utils.IsInteractive() {
+			return fmt.Errorf("no config found and no terminal attached: setup requires an interactive terminal")
+		}
+		cfg, err = runSetupWizard()
+		if err != nil {
+			return err
+		}
+	} else if err != nil {
+		return err
+	}
+
+	if c.Bool("export-repo-url") {
+		fmt.Println(cfg.SanitizedRepoURL())
+		return nil
+	}
+
+	switch {
+	case c.Bool("prefer-https") && c.Bool("prefer-ssh"):
+		return fmt.Errorf("--prefer-https and --prefer-ssh are mutually exclusive")
+	case c.Bool("prefer-https"):
+		if cfg.PAT == "" {
+			return fmt.Errorf("--prefer-https requires a stored PAT")
+		}
+		config.SetAuthMethodOverride("https")
+		utils.LogMessage("Using HTTPS auth for this run (--prefer-https)")
+	case c.Bool("prefer-ssh"):
+		if !config.SuggestSSH() {
+			return fmt.Errorf("--prefer-ssh requires an SSH key in ~/.ssh (id_ed25519, id_ecdsa, or id_rsa)")
+		}
+		config.SetAuthMethodOverride("ssh")
+		utils.LogMessage("Using SSH auth for this run (--prefer-ssh)")
+	}
+
+	if err := applyRunConfig(cfg); err != nil {
+		return err
+	}
+
+	if c.Bool("status") {
+		return runStatus(cfg)
+	}
+
+	if c.Bool("list") {
+		return runList(c.Int("list-count"))
+	}
+
+	if err := cfg.ApplyHostPaths(); err != nil {
+		utils.LogMessage("Could not resolve hostname for paths_by_host: %v", err)
+	}
+	if err := cfg.MergePathsFile(); err != nil {
+		utils.LogMessage("Could not read ~/.giterdone-paths: %v", err)
+	}
+
+	if c.Bool("stdin-paths") {
+		paths, err := readStdinPaths()
+		if err != nil {
+			return err
+		}
+		if len(paths) == 0 {
+			return fmt.Errorf("--stdin-paths given but no paths were read from stdin")
+		}
+		utils.LogMessage("Using %d include paths from stdin (config IncludePaths bypassed)", len(paths))
+		cfg.IncludePaths = paths
+	}
+
+	if c.Bool("only-if-online") || cfg.OnlyIfOnline {
+		if !git.IsRemoteReachable(cfg.AuthenticatedRepoURL()) {
+			utils.LogMessage("Offline, skipping backup")
+			fmt.Println("Offline, skipping backup.")
+			return nil
+		}
+	}
+
+	if err := checkPATExpiry(cfg); err != nil {
+		return err
+	}
+
+	err = runBackup(cfg, dryRun)
+	if dryRun == "" {
+		notifyResult(cfg, err)
+	}
+	return err
+}
+
+// runConfigShow prints the fully-resolved effective config -- after the
+// paths file and provider detection are applied -- with secrets redacted. It
+// modifies nothing.
+func runConfigShow(c *cli.Context) error {
+	configDir, err := config.GetConfigDir()
+	if err != nil {
+		return err
+	}
+	if err := utils.InitLogger(configDir, c.Bool("verbose")); err != nil {
+		return err
+	}
+	cfg, err := config.LoadConfig()
+	if err != nil {
+		return err
+	}
+	if err := cfg.MergePathsFile(); err != nil {
+		utils.LogMessage("Could not read ~/.giterdone-paths: %v", err)
+	}
+
+	// Work on a copy so redaction can never leak back into a saved config.
+	resolved := *cfg
+	if resolved.PAT != "" {
+		resolved.PAT = "***"
+	}
+	resolved.RepoURL = resolved.SanitizedRepoURL()
+	resolved.Provider = resolved.EffectiveProvider()
+
+	if c.Bool("json") {
+		data, err := json.MarshalIndent(&resolved, "", "  ")
+		if err != nil {
+			return err
+		}
+		fmt.Println(string(data))
+		return nil
+	}
+	fmt.Printf("repo_url:   %s\n", resolved.RepoURL)
+	fmt.Printf("provider:   %s\n", resolved.Provider)
+	fmt.Printf("pat:        %s\n", resolved.PAT)
+	fmt.Printf("frequency:  %s\n", resolved.Frequency)
+	fmt.Printf("include_paths:\n")
+	for _, p := range resolved.IncludePaths {
+		fmt.Println("  " + p)
+	}
+	tmpl, err := resolved.CommitTemplate()
+	if err != nil {
+		tmpl = fmt.Sprintf("(error: %v)", err)
+	}
+	fmt.Printf("commit_message_template: %s\n", tmpl)
+	return nil
+}
+
+// printPaths prints the resolved config path and, when all is set, the log,
+// state, and repo paths as well.
+func printPaths(all bool) error {
+	configPath, err := config.GetConfigPath()
+	if err != nil {
+		return err
+	}
+	if !all {
+		fmt.Println(configPath)
+		return nil
+	}
+	configDir, err := config.GetConfigDir()
+	if err != nil {
+		return err
+	}
+	statePath, err := config.GetStatePath()
+	if err != nil {
+		return err
+	}
+	repoDir, err := config.GetRepoDir()
+	if err != nil {
+		return err
+	}
+	fmt.Printf("config: %s\n", configPath)
+	fmt.Printf("log:    %s\n", filepath.Join(configDir, "giterdone.log"))
+	fmt.Printf("state:  %s\n", statePath)
+	fmt.Printf("repo:   %s\n", repoDir)
+	return nil
+}
+
+// readStdinPaths reads newline-delimited include paths from stdin, skipping
+// blank lines. Excludes and size limits still apply during the scan.
+func readStdinPaths() ([]string, error) {
+	var paths []string
+	sc := bufio.NewScanner(os.Stdin)
+	for sc.Scan() {
+		if line := strings.TrimSpace(sc.Text()); line != "" {
+			paths = append(paths, line)
+		}
+	}
+	if err := sc.Err(); err != nil {
+		return nil, fmt.Errorf("could not read paths from stdin: %w", err)
+	}
+	return paths, nil
+}
+
+// sizeGrowthWarnBytes is the per-run repo growth above which we warn that a
+// large file may have slipped past the exclude filters.
+const sizeGrowthWarnBytes = 50 * 1024 * 1024
+
+// runStatus reports the current .git and working-tree sizes and the growth
+// since the last recorded run.
+// runList prints the last n backup commits from the local repo clone --
+// hash, date, message, and files changed. It reads only local history, so
+// it works the same under SSH or PAT auth.
+func runList(n int) error {
+	repoDir, err := config.GetRepoDir()
+	if err != nil {
+		return err
+	}
+	if _, err := os.Stat(filepath.Join(repoDir, ".git")); err != nil {
+		fmt.Println("No local backup repo yet; run a backup first.")
+		return nil
+	}
+	entries, err := git.LogEntries(repoDir, n)
+	if err != nil {
+		return err
+	}
+	if len(entries) == 0 {
+		fmt.Println("No backup commits yet.")
+		return nil
+	}
+	for _, e := range entries {
+		fmt.Printf("%s  %s  %-50s  %d files changed\n", e.Hash, e.Date, e.Subject, e.FilesChanged)
+	}
+	return nil
+}
+
+func runStatus(cfg *config.Config) error {
+	repoDir, err := config.GetRepoDir()
+	if err != nil {
+		return err
+	}
+	gitSize, workSize, err := measureRepoSizes(repoDir)
+	if err != nil {
+		return err
+	}
+	st, err := config.LoadState()
+	if err != nil {
+		return err
+	}
+
+	fmt.Printf("Git version: %s\n", git.InstalledVersion())
+	fmt.Printf("Backup repo: %s\n", repoDir)
+	if !git.IsGitRepo(repoDir) {
+		fmt.Println("  not initialized yet; run a backup first")
+	} else {
+		if dirty, err := git.IsGitDirty(repoDir); err == nil {
+			state := "clean"
+			if dirty {
+				state = "dirty (uncommitted changes)"
+			}
+			fmt.Printf("  work tree:         %s\n", state)
+		}
+		if origin, err := git.RemoteOriginURL(repoDir); err == nil {
+			fmt.Printf("  remote origin:     %s\n", utils.Redact(origin))
+		}
+		if last := git.LastCommitTime(repoDir); last != "" {
+			fmt.Printf("  last backup commit: %s\n", last)
+		}
+	}
+	if cron.JobInstalled(cfg.Scheduler) {
+		fmt.Println("Scheduled job: installed")
+	} else {
+		fmt.Println("Scheduled job: NOT installed")
+	}
+	fmt.Printf("  .git size:         %s\n", utils.HumanSize(gitSize))
+	fmt.Printf("  working-tree size: %s\n", utils.HumanSize(workSize))
+	if st.LastGitDirSize > 0 {
+		delta := gitSize - st.LastGitDirSize
+		fmt.Printf("  growth since last run: %s\n", utils.HumanSize(delta))
+		if delta > sizeGrowthWarnBytes {
+			fmt.Println("  Warning: the repo grew unusually fast; a large binary may have slipped through the exclude filters.")
+		}
+	}
+	if cfg.Frequency != "" {
+		for _, line := range cron.NextRunsSummary(cfg.Frequency, 3) {
+			fmt.Println(line)
+		}
+	}
+	return nil
+}
+
+// measureRepoSizes returns the sizes of the repo's .git directory and of its
+// working tree (excluding .git).
+func measureRepoSizes(repoDir string) (gitSize, workSize int64, err error) {
+	gitSize, err = utils.DirSize(filepath.Join(repoDir, ".git"))
+	if err != nil {
+		return 0, 0, err
+	}
+	total, err := utils.DirSize(repoDir)
+	if err != nil {
+		return 0, 0, err
+	}
+	return gitSize, total - gitSize, nil
+}
+
+// recordRepoSizes updates state with the post-backup repo sizes and warns if
+// the repo grew suspiciously fast this run.
+func recordRepoSizes(repoDir string) {
+	gitSize, workSize, err := measureRepoSizes(repoDir)
+	if err != nil {
+		utils.LogMessage("Could not measure repo size: %v", err)
+		return
+	}
+	st, err := config.LoadState()
+	if err != nil {
+		utils.LogMessage("Could not load state: %v", err)
+		return
+	}
+	if st.LastGitDirSize > 0 {
+		delta := gitSize - st.LastGitDirSize
+		utils.LogMessage("Repo size: .git %s, working tree %s (delta %s)",
+			utils.HumanSize(gitSize), utils.HumanSize(workSize), utils.HumanSize(delta))
+		if delta > sizeGrowthWarnBytes {
+			utils.LogMessage("Warning: repo grew by %s this run; a large binary may have slipped through the exclude filters", utils.HumanSize(delta))
+		}
+	}
+	st.LastGitDirSize = gitSize
+	st.LastWorkTreeSize = workSize
+	if err := config.SaveState(st); err != nil {
+		utils.LogMessage("Could not save state: %v", err)
+	}
+}
+
+// runListExcluded audits the scanner's exclusion decisions: it scans the
+// include paths and prints every skipped file grouped by reason, without any
+// git operations.
+func runListExcluded(c *cli.Context) error {
+	configDir, err := config.GetConfigDir()
+	if err != nil {
+		return err
+	}
+	if err := utils.InitLogger(configDir, c.Bool("verbose")); err != nil {
+		return err
+	}
+	cfg, err := config.LoadConfig()
+	if err != nil {
+		return err
+	}
+	excluded, err := scanner.ListExcluded(cfg)
+	if err != nil {
+		return err
+	}
+	if len(excluded) == 0 {
+		fmt.Println("No files are excluded.")
+		return nil
+	}
+	var reasons []string
+	for reason := range excluded {
+		reasons = append(reasons, reason)
+	}
+	sort.Strings(reasons)
+	for _, reason := range reasons {
+		fmt.Printf("Excluded by %s:\n", reason)
+		for _, path := range excluded[reason] {
+			fmt.Println("  " + path)
+		}
+	}
+	return nil
+}
+
+// runMigrateRemote moves the backup to a new remote: updates the config,
+// repoints origin, pushes all branches and tags, and verifies connectivity.
+func runMigrateRemote(c *cli.Context) error {
+	newURL := strings.TrimSpace(c.Args().First())
+	if newURL == "" {
+		return fmt.Errorf("usage: giterdone migrate-remote <new-url>")
+	}
+
+	configDir, err := config.GetConfigDir()
+	if err != nil {
+		return err
+	}
+	if err := utils.InitLogger(configDir, c.Bool("verbose")); err != nil {
+		return err
+	}
+	cfg, err := config.LoadConfig()
+	if err != nil {
+		return err
+	}
+	repoDir, err := config.GetRepoDir()
+	if err != nil {
+		return err
+	}
+	if !git.IsGitRepo(repoDir) {
+		return fmt.Errorf("no local backup repo at %s; run a backup first", repoDir)
+	}
+
+	oldURL := cfg.RepoURL
+	cfg.RepoURL = newURL
+	if strings.HasPrefix(newURL, "https://") {
+		patPrompt := promptui.Prompt{Label: "Personal access token for the new provider (leave blank to keep current)", Mask: '*'}
+		pat, err := patPrompt.Run()
+		if err != nil {
+			return err
+		}
+		if pat = strings.TrimSpace(pat); pat != "" {
+			cfg.PAT = pat
+		}
+	}
+
+	authURL := cfg.AuthenticatedRepoURL()
+	if err := git.LsRemote(authURL); err != nil {
+		return fmt.Errorf("new remote is not reachable: %w", err)
+	}
+	if err := git.SetRemoteOrigin(repoDir, authURL); err != nil {
+		return err
+	}
+	utils.LogMessage("Pushing all branches and tags to %s", newURL)
+	if err := git.PushAll(repoDir, "origin"); err != nil {
+		return err
+	}
+	if c.Bool("keep-old-as-mirror") {
+		if err := git.AddRemote(repoDir, "mirror", oldURL); err != nil {
+			utils.LogMessage("Could not keep old remote as mirror: %v", err)
+		} else {
+			utils.LogMessage("Old remote kept as 'mirror' (%s)", oldURL)
+		}
+	}
+	if err := config.SaveConfig(cfg); err != nil {
+		return err
+	}
+	fmt.Printf("Migrated backup remote to %s\n", newURL)
+	return nil
+}
+
+// runSetupWizard interactively builds a config, saves it, and installs the
+// cron job.
+func runSetupWizard() (*config.Config, error) {
+	fmt.Println("No config found -- starting setup.")
+
+	cfg := &config.Config{}
+
+	urlPrompt := promptui.Prompt{Label: "Git repo URL (SSH or HTTPS)"}
+	url, err := urlPrompt.Run()
+	if err != nil {
+		return nil, err
+	}
+	cfg.RepoURL = strings.TrimSpace(url)
+
+	if strings.HasPrefix(cfg.RepoURL, "https://") {
+		patPrompt := promptui.Prompt{Label: "Personal access token (leave blank for none)", Mask: '*'}
+		pat, err := patPrompt.Run()
+		if err != nil {
+			return nil, err
+		}
+		cfg.PAT = strings.TrimSpace(pat)
+	}
+
+	if cfg.PAT != "" && config.KeyringAvailable() {
+		useKeyring, err := confirm("Store the token in the OS keyring (config file then holds no secret)")
+		if err != nil {
+			return nil, err
+		}
+		if useKeyring {
+			if err := config.KeyringStore(cfg.RepoURL, cfg.PAT); err != nil {
+				return nil, err
+			}
+			// The in-memory PAT stays for this run; SaveConfig strips it.
+			cfg.PATSource = "keyring"
+		}
+	}
+
+	if !strings.HasPrefix(cfg.RepoURL, "https://") {
+		if keys := config.DetectSSHKeys(); len(keys) > 0 {
+			keySelect := promptui.Select{
+				Label: "SSH key to use",
+				Items: append([]string{"let ssh choose"}, keys...),
+			}
+			_, choice, err := keySelect.Run()
+			if err != nil {
+				return nil, err
+			}
+			if choice != "let ssh choose" {
+				cfg.SSHKeyPath = choice
+			}
+		}
+	}
+
+	pathsPrompt := promptui.Prompt{Label: "Paths to back up (comma-separated)"}
+	paths, err := pathsPrompt.Run()
+	if err != nil {
+		return nil, err
+	}
+	for _, p := range strings.Split(paths, ",") {
+		if p = strings.TrimSpace(p); p != "" {
+			cfg.IncludePaths = append(cfg.IncludePaths, p)
+		}
+	}
+
+	fmt.Println("Default exclude patterns:")
+	for _, p := range scanner.DefaultExcludePatterns() {
+		fmt.Println("  " + p)
+	}
+	genIgnore, err := confirm("Generate a .gitignore with these excludes")
+	if err != nil {
+		return nil, err
+	}
+	if !genIgnore {
+		f := false
+		cfg.GenerateGitignore = &f
+	}
+
+	extraPrompt := promptui.Prompt{Label: "Extra exclude patterns (comma-separated, blank for none)"}
+	extra, err := extraPrompt.Run()
+	if err != nil {
+		return nil, err
+	}
+	for _, p := range strings.Split(extra, ",") {
+		if p = strings.TrimSpace(p); p != "" {
+			cfg.ExcludePatterns = append(cfg.ExcludePatterns, p)
+		}
+	}
+
+	tmplPrompt := promptui.Prompt{
+		Label:   "Commit message template",
+		Default: utils.DefaultCommitTemplate,
+	}
+	tmpl, err := tmplPrompt.Run()
+	if err != nil {
+		return nil, err
+	}
+	cfg.CommitMessageTemplate = tmpl
+
+	sizePrompt := promptui.Prompt{Label: "Max file size to back up", Default: "100MB"}
+	sizeStr, err := sizePrompt.Run()
+	if err != nil {
+		return nil, err
+	}
+	maxSize, err := utils.ParseHumanSize(sizeStr)
+	if err != nil {
+		return nil, err
+	}
+	if maxSize != 100*1024*1024 {
+		cfg.MaxFileSizeBytes = maxSize
+	}
+
+	branchPrompt := promptui.Prompt{Label: "Branch to push backups to", Default: "main"}
+	branch, err := branchPrompt.Run()
+	if err != nil {
+		return nil, err
+	}
+	if branch = strings.TrimSpace(branch); branch != "" && branch != "main" {
+		cfg.Branch = branch
+	}
+
+	// Default the commit identity from the system so scheduled commits
+	// succeed even on boxes with no global git identity.
+	defaultName := "giterdone"
+	if u, err := user.Current(); err == nil && u.Username != "" {
+		defaultName = u.Username
+	}
+	hostname, _ := os.Hostname()
+	namePrompt := promptui.Prompt{Label: "Commit author name", Default: defaultName}
+	authorName, err := namePrompt.Run()
+	if err != nil {
+		return nil, err
+	}
+	cfg.CommitAuthorName = strings.TrimSpace(authorName)
+	emailPrompt := promptui.Prompt{Label: "Commit author email", Default: defaultName + "@" + hostname}
+	authorEmail, err := emailPrompt.Run()
+	if err != nil {
+		return nil, err
+	}
+	cfg.CommitAuthorEmail = strings.TrimSpace(authorEmail)
+
+	signPrompt := promptui.Prompt{Label: "GPG signing key ID (leave blank for unsigned commits)"}
+	signKey, err := signPrompt.Run()
+	if err != nil {
+		return nil, err
+	}
+	if signKey = strings.TrimSpace(signKey); signKey != "" {
+		if git.HasSecretKey(signKey) {
+			cfg.SigningKey = signKey
+		} else {
+			fmt.Printf("Warning: key %s not found in gpg --list-secret-keys; commits will not be signed.\n", signKey)
+		}
+	}
+
+	freqSelect := promptui.Select{
+		Label: "Backup frequency",
+		Items: []string{"hourly", "daily", "weekly", "custom cron spec"},
+	}
+	_, freq, err := freqSelect.Run()
+	if err != nil {
+		return nil, err
+	}
+	if freq == "custom cron spec" {
+		specPrompt := promptui.Prompt{
+			Label:    "Cron spec (minute hour day-of-month month day-of-week)",
+			Validate: cron.ValidateSpec,
+		}
+		if freq, err = specPrompt.Run(); err != nil {
+			return nil, err
+		}
+	}
+	cfg.Frequency = freq
+
+	if err := config.SaveConfig(cfg); err != nil {
+		return nil, err
+	}
+
+	fmt.Println("Testing remote connectivity...")
+	if err := checkRemoteAuth(cfg); err != nil {
+		fmt.Printf("Warning: %v\n", err)
+	} else {
+		fmt.Println("Remote connectivity: OK")
+	}
+
+	installCron, err := confirm("Install a scheduled job to run backups automatically")
+	if err != nil {
+		return nil, err
+	}
+	if installCron {
+		appPath, err := os.Executable()
+		if err != nil {
+			return nil, err
+		}
+		sched, err := cron.SchedulerFor(cfg.Scheduler)
+		if err != nil {
+			return nil, err
+		}
+		if err := sched.Install(cfg.Frequency, appPath); err != nil {
+			return nil, err
+		}
+	}
+
+	fmt.Println("Setup complete.")
+	return cfg, nil
+}
+
+// runBackup performs a full scan/mirror/commit/push cycle. dryRun may be
+// empty (real run), "offline" (no git operations), or "remote" (validate
+// connectivity and diff against the remote without committing or pushing).
+// followSymlinks mirrors cfg.FollowSymlinks for the file-copying helpers,
+// set once per backup run.
+var followSymlinks bool
+
+func runBackup(cfg *config.Config, dryRun string) error {
+	if err := checkSafeMode(cfg); err != nil {
+		return err
+	}
+	// Catch a typo'd hash_algorithm up front rather than silently hashing
+	// nothing inside the worker pool.
+	if _, err := manifest.NewHash(cfg.HashAlgorithm); err != nil {
+		return err
+	}
+	followSymlinks = cfg.FollowSymlinks
+	copyBufferBytes = cfg.CopyBufferKB * 1024
+	backend, err := storageBackendFor(cfg)
+	if err != nil {
+		return err
+	}
+	if backend != nil {
+		return runArchiveBackup(cfg, dryRun, backend)
+	}
+	if cfg.SingleRepoMode {
+		return runSingleRepoBackup(cfg, dryRun)
+	}
+
+	repoDir, err := config.GetRepoDir()
+	if err != nil {
+		return err
+	}
+
+	if dryRun != "offline" {
+		if !git.IsGitRepo(repoDir) {
+			if err := ensureRemoteRepo(cfg); err != nil {
+				return err
+			}
+			if err := git.CloneRepo(cfg.AuthenticatedRepoURL(), repoDir); err != nil {
+				// Self-managed bare repos may be completely empty, which
+				// some git versions refuse to clone; fall back to init.
+				if cfg.EffectiveProvider() != "generic" {
+					return err
+				}
+				utils.LogMessage("Clone failed (%v); initializing fresh repo for generic remote", err)
+				if initErr := git.InitRepo(repoDir, cfg.AuthenticatedRepoURL()); initErr != nil {
+					return initErr
+				}
+			}
+		} else if dirty, err := git.IsGitDirty(repoDir); err == nil && dirty {
+			if err := handleDirtyRepo(cfg, repoDir); err != nil {
+				return err
+			}
+		}
+		if cfg.BranchPerHost {
+			hostname, err := os.Hostname()
+			if err != nil {
+				return err
+			}
+			branch := git.SanitizeBranchName(hostname)
+			if err := git.CheckoutBranch(repoDir, branch); err != nil {
+				return err
+			}
+			git.ConfigureTargetBranch(branch)
+		} else if cfg.Branch != "" {
+			if err := git.CheckoutBranch(repoDir, cfg.Branch); err != nil {
+				return err
+			}
+			git.ConfigureTargetBranch(cfg.Branch)
+		}
+		// Rebase before mirroring dirties the work tree, so remote-side
+		// edits never leave the eventual push rejected.
+		if cfg.ShouldPullBeforePush() {
+			if err := git.RebaseOntoRemote(repoDir); err != nil {
+				return err
+			}
+		}
+	}
+
+	filesToInclude, patternsToExclude, err := scanner.ScanFiles(cfg)
+	if err != nil {
+		return err
+	}
+	lastRun.filesScanned = len(filesToInclude)
+	utils.LogMessage("Scanned %d files to back up", len(filesToInclude))
+
+	if dryRun == "offline" {
+		if err := printDryRunDiff(cfg, repoDir, filesToInclude); err != nil {
+			return err
+		}
+		if dryRunReportPath != "" {
+			return writeDryRunReport(cfg, filesToInclude)
+		}
+		return nil
+	}
+
+	if dryRun == "remote" {
+		return runRemoteDryRun(cfg, repoDir, filesToInclude, patternsToExclude)
+	}
+
+	if len(cfg.Validators) > 0 {
+		filesToInclude, err = validateFiles(cfg, filesToInclude)
+		if err != nil {
+			return err
+		}
+	}
+
+	if err := scanFilesForSecrets(cfg, filesToInclude); err != nil {
+		return err
+	}
+
+	subpath, err := resolvedRepoSubpath(cfg)
+	if err != nil {
+		return err
+	}
+	mirrorRoot := repoDir
+	if subpath != "" {
+		mirrorRoot = filepath.Join(repoDir, subpath)
+	}
+
+	var repoFiles []string
+	if cfg.DedupFiles {
+		repoFiles, err = mirrorFilesDeduped(cfg, mirrorRoot, filesToInclude)
+		if err != nil {
+			return err
+		}
+	} else {
+		repoFiles = mirrorFiles(mirrorRoot, filesToInclude)
+	}
+	if subpath != "" {
+		// git add runs from the repo root, so re-anchor the mirrored paths.
+		for i, rel := range repoFiles {
+			repoFiles[i] = filepath.Join(subpath, rel)
+		}
+	}
+	repoFiles = append(repoFiles, runConfiguredCommands(cfg, repoDir)...)
+
+	if cfg.PreserveXattrs {
+		rel, err := captureXattrs(repoDir, filesToInclude)
+		if err != nil {
+			return err
+		}
+		if rel != "" {
+			repoFiles = append(repoFiles, rel)
+		}
+	}
+
+	if cfg.ShouldGenerateGitignore() {
+		if err := scanner.WriteGitignoreFile(repoDir, patternsToExclude); err != nil {
+			return err
+		}
+		repoFiles = append(repoFiles, ".gitignore")
+	}
+	if len(cfg.MergeStrategies) > 0 {
+		if err := scanner.WriteGitAttributesFile(repoDir, cfg.MergeStrategies); err != nil {
+			return err
+		}
+		repoFiles = append(repoFiles, ".gitattributes")
+	}
+
+	tmpl, err := cfg.CommitTemplate()
+	if err != nil {
+		return err
+	}
+	msg, err := utils.GenerateCommitMessage(tmpl, len(filesToInclude))
+	if err != nil {
+		return err
+	}
+	headBefore := git.Head(repoDir)
+	if cfg.CommitPerPath {
+		if err := commitPerPath(cfg, repoDir, subpath, repoFiles, msg); err != nil {
+			return err
+		}
+	} else {
+		if err := git.AddFiles(repoDir, repoFiles); err != nil {
+			return err
+		}
+		if err := git.Commit(repoDir, msg); err != nil {
+			return err
+		}
+	}
+	if cfg.EmptyCommitHeartbeat && git.Head(repoDir) == headBefore {
+		utils.LogMessage("No changes this run; recording heartbeat commit")
+		if err := git.CommitAllowEmpty(repoDir, "Heartbeat: "+msg); err != nil {
+			return err
+		}
+	}
+	if head := git.Head(repoDir); head != headBefore {
+		lastRun.committed = true
+		lastRun.commitHash = head
+	}
+	squashed, err := maybeSquashCommits(cfg, repoDir)
+	if err != nil {
+		return err
+	}
+	switch {
+	case squashed:
+		// A squash rewrote local history; deferring would leave the remote
+		// diverged, so it always pushes.
+		if err := git.PushForceWithLease(repoDir); err != nil {
+			return err
+		}
+	case !shouldPush(cfg):
+		recordRepoSizes(repoDir)
+		utils.LogMessage("Backup complete (push deferred)")
+		return nil
+	default:
+		if err := git.Push(repoDir); err != nil {
+			return err
+		}
+	}
+	recordPush()
+	lastRun.pushed = true
+	recordRepoSizes(repoDir)
+	utils.LogMessage("Backup complete")
+	return nil
+}
+
+// shouldPush reports whether this run's commits should be pushed now, or
+// left to accumulate locally because push_every_minutes hasn't elapsed
+// since the last successful push.
+func shouldPush(cfg *config.Config) bool {
+	if forcePush || cfg.PushEveryMinutes <= 0 {
+		return true
+	}
+	st, err := config.LoadState()
+	if err != nil {
+		utils.LogMessage("Could not load state for push deferral: %v", err)
+		return true
+	}
+	if st.LastPushUnix == 0 {
+		return true
+	}
+	interval := time.Duration(cfg.PushEveryMinutes) * time.Minute
+	elapsed := time.Since(time.Unix(st.LastPushUnix, 0))
+	if elapsed >= interval {
+		return true
+	}
+	msg := fmt.Sprintf("Commit made, push deferred (next push in %s).", (interval - elapsed).Round(time.Minute))
+	utils.LogMessage("%s", msg)
+	fmt.Println(msg)
+	return false
+}
+
+// recordPush stamps the successful push time into state for shouldPush.
+func recordPush() {
+	st, err := config.LoadState()
+	if err != nil {
+		utils.LogMessage("Could not load state to record push time: %v", err)
+		return
+	}
+	st.LastPushUnix = time.Now().Unix()
+	if err := config.SaveState(st); err != nil {
+		utils.LogMessage("Could not save push time: %v", err)
+	}
+}
+
+// checkSafeMode refuses to run when a history-rewriting feature is enabled
+// while safe mode (the default) is on. Every feature that would force-push
+// must be listed here so the guardrail stays exhaustive.
+func checkSafeMode(cfg *config.Config) error {
+	if !cfg.IsSafeMode() {
+		return nil
+	}
+	if cfg.SquashEveryNCommits > 0 {
+		return fmt.Errorf("squash_every_n_commits rewrites history and force-pushes; set safe_mode to false to allow it")
+	}
+	return nil
+}
+
+// maybeSquashCommits squashes the last N backup commits into one checkpoint
+// commit when squash_every_n_commits is due, per the counter in state. It
+// reports whether history was rewritten so the caller force-pushes (with
+// lease) instead of pushing normally.
+func maybeSquashCommits(cfg *config.Config, repoDir string) (bool, error) {
+	if cfg.SquashEveryNCommits <= 0 || !lastRun.committed {
+		return false, nil
+	}
+	st, err := config.LoadState()
+	if err != nil {
+		return false, err
+	}
+	st.CommitsSinceSquash++
+	n := cfg.SquashEveryNCommits
+	// Never reach past the root commit: a repo younger than N commits (or
+	// freshly recloned, losing the counter's context) just keeps counting.
+	if st.CommitsSinceSquash < n || git.CommitCount(repoDir) <= n {
+		return false, config.SaveState(st)
+	}
+
+	subjects, err := git.LogSubjects(repoDir, n)
+	if err != nil {
+		return false, err
+	}
+	msg := fmt.Sprintf("Checkpoint: squash of %d backups\n\n%s", n, strings.Join(subjects, "\n"))
+	if err := git.ResetSoft(repoDir, fmt.Sprintf("HEAD~%d", n)); err != nil {
+		return false, err
+	}
+	if err := git.Commit(repoDir, msg); err != nil {
+		return false, err
+	}
+	lastRun.commitHash = git.Head(repoDir)
+	utils.LogMessage("Squashed last %d commits into checkpoint %s", n, lastRun.commitHash)
+	st.CommitsSinceSquash = 0
+	return true, config.SaveState(st)
+}
+
+// backupSummary captures what the last backup run did, for synchronous
+// reporting by `backup-now`.
+type backupSummary struct {
+	filesScanned int
+	committed    bool
+	commitHash   string
+	pushed       bool
+}
+
+var lastRun backupSummary
+
+// runBackupNow runs a backup with rich synchronous reporting to stdout --
+// the "I just want to see it work" entry point, as opposed to the silent
+// cron-oriented default action.
+func runBackupNow(c *cli.Context) error {
+	configDir, err := config.GetConfigDir()
+	if err != nil {
+		return err
+	}
+	if err := utils.InitLogger(configDir, true); err != nil {
+		return err
+	}
+	cfg, err := config.LoadConfig()
+	if err != nil {
+		return err
+	}
+	if err := cfg.MergePathsFile(); err != nil {
+		utils.LogMessage("Could not read ~/.giterdone-paths: %v", err)
+	}
+	if err := applyRunConfig(cfg); err != nil {
+		return err
+	}
+
+	if err := runBackup(cfg, ""); err != nil {
+		notifyResult(cfg, err)
+		fmt.Printf("Backup FAILED: %v\n", err)
+		return err
+	}
+	notifyResult(cfg, nil)
+
+	fmt.Printf("Files scanned:  %d\n", lastRun.filesScanned)
+	if lastRun.committed {
+		fmt.Printf("Committed:      %s\n", lastRun.commitHash)
+		if url := commitWebURL(cfg, lastRun.commitHash); url != "" {
+			fmt.Printf("Commit URL:     %s\n", url)
+		}
+	} else {
+		fmt.Println("Committed:      no changes")
+	}
+	fmt.Printf("Pushed:         %v\n", lastRun.pushed)
+	return nil
+}
+
+// commitWebURL builds the provider's web URL for a commit, when the repo URL
+// is a browsable HTTPS remote.
+func commitWebURL(cfg *config.Config, hash string) string {
+	url := strings.TrimSuffix(cfg.RepoURL, ".git")
+	if !strings.HasPrefix(url, "https://") {
+		return ""
+	}
+	switch cfg.EffectiveProvider() {
+	case "github", "gitlab":
+		return url + "/commit/" + hash
+	default:
+		return ""
+	}
+}
+
+// handleDirtyRepo applies the configured policy when the backup repo has
+// unexpected uncommitted changes at the start of a run.
+func handleDirtyRepo(cfg *config.Config, repoDir string) error {
+	switch cfg.DirtyRepoPolicy {
+	case "", "warn":
+		utils.LogMessage("Warning: backup repo has uncommitted changes; proceeding anyway")
+		return nil
+	case "abort":
+		return fmt.Errorf("backup repo %s has uncommitted changes and dirty_repo_policy is 'abort'", repoDir)
+	case "stash":
+		utils.LogMessage("Backup repo is dirty; stashing uncommitted changes (dirty_repo_policy=stash)")
+		return git.Stash(repoDir)
+	case "commit-all":
+		utils.LogMessage("Backup repo is dirty; committing leftover changes (dirty_repo_policy=commit-all)")
+		if err := git.AddAll(repoDir); err != nil {
+			return err
+		}
+		return git.Commit(repoDir, "giterdone: commit leftover uncommitted changes")
+	default:
+		return fmt.Errorf("unknown dirty_repo_policy %q (expected warn, abort, stash, or commit-all)", cfg.DirtyRepoPolicy)
+	}
+}
+
+// commitPerPath stages and commits each include path's files separately with
+// a path-scoped message, leaving one final commit for anything not under an
+// include path (command output, .gitignore, manifest). Paths with no changes
+// produce no commit. Include paths are matched in their expanded form, and
+// the repo_subpath re-anchoring is undone before comparing, so grouping
+// works the same however the paths were configured.
+func commitPerPath(cfg *config.Config, repoDir, subpath string, repoFiles []string, msg string) error {
+	remaining := append([]string{}, repoFiles...)
+	for _, includePath := range scanner.ExpandIncludePaths(cfg.IncludePaths) {
+		var group, rest []string
+		for _, rel := range remaining {
+			srcRel := rel
+			if subpath != "" {
+				if !strings.HasPrefix(rel, subpath+"/") {
+					rest = append(rest, rel)
+					continue
+				}
+				srcRel = strings.TrimPrefix(rel, subpath+"/")
+			}
+			abs := "/" + srcRel
+			if abs == includePath || strings.HasPrefix(abs, strings.TrimSuffix(includePath, "/")+"/") {
+				group = append(group, rel)
+			} else {
+				rest = append(rest, rel)
+			}
+		}
+		remaining = rest
+		if len(group) == 0 {
+			continue
+		}
+		if err := git.AddFiles(repoDir, group); err != nil {
+			return err
+		}
+		if err := git.Commit(repoDir, fmt.Sprintf("%s [%s]", msg, includePath)); err != nil {
+			return err
+		}
+	}
+	if err := git.AddAll(repoDir); err != nil {
+		return err
+	}
+	return git.Commit(repoDir, msg)
+}
+
+// runSingleRepoBackup handles the classic dotfiles-repo workflow: the single
+// include path is itself the backup repo, so we `git add -A` there directly
+// instead of mirroring into a managed clone.
+func runSingleRepoBackup(cfg *config.Config, dryRun string) error {
+	if len(cfg.IncludePaths) != 1 {
+		return fmt.Errorf("single_repo_mode requires exactly one include path, got %d", len(cfg.IncludePaths))
+	}
+	repoDir := config.ExpandUserPath(cfg.IncludePaths[0])
+	if !git.IsGitRepo(repoDir) {
+		return fmt.Errorf("single_repo_mode is set but %s is not a git repository", repoDir)
+	}
+
+	if cfg.ShouldGenerateGitignore() {
+		if _, err := os.Stat(filepath.Join(repoDir, ".gitignore")); os.IsNotExist(err) {
+			if err := scanner.WriteGitignoreFile(repoDir, scanner.DefaultExcludePatterns()); err != nil {
+				return err
+			}
+		}
+	}
+
+	if dryRun != "" {
+		dirty, err := git.IsGitDirty(repoDir)
+		if err != nil {
+			return err
+		}
+		if dirty {
+			fmt.Println("Dry run: repo has changes that would be committed and pushed.")
+		} else {
+			fmt.Println("Dry run: nothing to commit.")
+		}
+		return nil
+	}
+
+	if err := git.SetRemoteOrigin(repoDir, cfg.AuthenticatedRepoURL()); err != nil {
+		utils.LogMessage("Could not update origin URL: %v", err)
+	}
+	if err := git.AddAll(repoDir); err != nil {
+		return err
+	}
+	tmpl, err := cfg.CommitTemplate()
+	if err != nil {
+		return err
+	}
+	// Single-repo mode never scans, so no file count is available.
+	msg, err := utils.GenerateCommitMessage(tmpl, 0)
+	if err != nil {
+		return err
+	}
+	if err := git.Commit(repoDir, msg); err != nil {
+		return err
+	}
+	if !shouldPush(cfg) {
+		utils.LogMessage("Backup complete (single-repo mode, push deferred)")
+		return nil
+	}
+	if err := git.Push(repoDir); err != nil {
+		return err
+	}
+	recordPush()
+	utils.LogMessage("Backup complete (single-repo mode)")
+	return nil
+}
+
+// printDryRunDiff classifies the scanned files against the local repo
+// mirror -- newly tracked, modified, or unchanged -- and prints the commit
+// message a real run would use. Everything is read-only: the index, the
+// work tree, and the remote are never touched, so it is safe to run while
+// verifying include paths before trusting the scheduled job.
+func printDryRunDiff(cfg *config.Config, repoDir string, filesToInclude []string) error {
+	subpath, err := resolvedRepoSubpath(cfg)
+	if err != nil {
+		return err
+	}
+	mirrorRoot := repoDir
+	if subpath != "" {
+		mirrorRoot = filepath.Join(repoDir, subpath)
+	}
+	var added, modified []string
+	unchanged := 0
+	for _, src := range filesToInclude {
+		dest := filepath.Join(mirrorRoot, strings.TrimPrefix(src, "/"))
+		destInfo, err := os.Stat(dest)
+		if err != nil {
+			added = append(added, src)
+			continue
+		}
+		srcInfo, err := os.Stat(src)
+		if err != nil {
+			continue
+		}
+		if destInfo.Size() != srcInfo.Size() {
+			modified = append(modified, src)
+			continue
+		}
+		// Same size: only a content hash can tell them apart.
+		srcHash, srcErr := manifest.HashFileWith(src, cfg.HashAlgorithm)
+		destHash, destErr := manifest.HashFileWith(dest, cfg.HashAlgorithm)
+		if srcErr != nil || destErr != nil || srcHash != destHash {
+			modified = append(modified, src)
+		} else {
+			unchanged++
+		}
+	}
+
+	fmt.Printf("Dry run: %d new, %d modified, %d unchanged.\n", len(added), len(modified), unchanged)
+	if len(added) > 0 {
+		fmt.Println("Newly tracked:")
+		for _, f := range added {
+			fmt.Println("  A " + f)
+		}
+	}
+	if len(modified) > 0 {
+		fmt.Println("Modified:")
+		for _, f := range modified {
+			fmt.Println("  M " + f)
+		}
+	}
+	tmpl, err := cfg.CommitTemplate()
+	if err != nil {
+		return err
+	}
+	msg, err := utils.GenerateCommitMessage(tmpl, len(filesToInclude))
+	if err != nil {
+		return err
+	}
+	fmt.Printf("Commit message would be: %s\n", msg)
+	return nil
+}
+
+// runRemoteDryRun validates connectivity and auth against the real remote
+// and reports the would-be diff, without committing or pushing.
+func runRemoteDryRun(cfg *config.Config, repoDir string, filesToInclude, patternsToExclude []string) error {
+	if err := git.LsRemote(cfg.AuthenticatedRepoURL()); err != nil {
+		return fmt.Errorf("remote connectivity check failed: %w", err)
+	}
+	fmt.Println("Remote is reachable and credentials are valid.")
+
+	// The mirror and diff happen in a throwaway clone of the local repo, so
+	// the dry run never leaves copied files or staged changes behind in the
+	// live work tree.
+	scratch, err := os.MkdirTemp("", "giterdone-dryrun-")
+	if err != nil {
+		return err
+	}
+	defer os.RemoveAll(scratch)
+	scratchRepo := filepath.Join(scratch, "repo")
+	if err := git.CloneLocal(repoDir, scratchRepo); err != nil {
+		return err
+	}
+	if err := git.FetchURL(scratchRepo, cfg.AuthenticatedRepoURL()); err != nil {
+		return err
+	}
+	subpath, err := resolvedRepoSubpath(cfg)
+	if err != nil {
+		return err
+	}
+	mirrorRoot := scratchRepo
+	if subpath != "" {
+		mirrorRoot = filepath.Join(scratchRepo, subpath)
+	}
+	mirrorFiles(mirrorRoot, filesToInclude)
+	if cfg.ShouldGenerateGitignore() {
+		if err := scanner.WriteGitignoreFile(scratchRepo, patternsToExclude); err != nil {
+			return err
+		}
+	}
+	if err := git.AddAll(scratchRepo); err != nil {
+		return err
+	}
+
+	stat, err := git.DiffCachedStat(scratchRepo, "FETCH_HEAD")
+	if err != nil {
+		return err
+	}
+	if strings.TrimSpace(stat) == "" {
+		fmt.Println("Dry run (remote): no changes against the remote HEAD.")
+	} else {
+		fmt.Println("Dry run (remote): changes against the remote HEAD:")
+		fmt.Print(stat)
+	}
+	if dryRunReportPath != "" {
+		return writeDryRunReport(cfg, filesToInclude)
+	}
+	return nil
+}
+
+// validateFiles runs the configured validators against matching files before
+// commit, so broken configs are never snapshotted. Invalid files are
+// excluded, or abort the run when validator_policy is "abort".
+func validateFiles(cfg *config.Config, files []string) ([]string, error) {
+	var valid []string
+	for _, path := range files {
+		ok := true
+		for glob, validator := range cfg.Validators {
+			if matched, _ := filepath.Match(glob, filepath.Base(path)); !matched {
+				continue
+			}
+			ctx, cancel := context.WithTimeout(context.Background(), commandTimeout)
+			err := exec.CommandContext(ctx, "sh", "-c", validator+" "+shellQuote(path)).Run()
+			cancel()
+			if err != nil {
+				if cfg.ValidatorPolicy == "abort" {
+					return nil, fmt.Errorf("validator %q failed for %s: %w", validator, path, err)
+				}
+				utils.LogMessage("Excluding %s: validator %q failed (%v)", path, validator, err)
+				ok = false
+				break
+			}
+			utils.LogMessage("Validated %s with %q", path, validator)
+		}
+		if ok {
+			valid = append(valid, path)
+		}
+	}
+	return valid, nil
+}
+
+// shellQuote wraps a path in single quotes for safe interpolation into an
+// sh -c command line.
+func shellQuote(s string) string {
+	return "'" + strings.ReplaceAll(s, "'", `'\''`) + "'"
+}
+
+// commandTimeout bounds how long a configured command may run before its
+// snapshot is abandoned.
+const commandTimeout = 60 * time.Second
+
+// runConfiguredCommands runs each configured command and captures its stdout
+// into a file in the repo. Failures are logged but never abort the backup.
+// It returns the repo-relative paths of the captured output files.
+func runConfiguredCommands(cfg *config.Config, repoDir string) []string {
+	var repoFiles []string
+	for _, spec := range cfg.Commands {
+		if spec.Cmd == "" || spec.OutputFile == "" {
+			utils.LogMessage("Skipping command %q: cmd and output_file are required", spec.Name)
+			continue
+		}
+		ctx, cancel := context.WithTimeout(context.Background(), commandTimeout)
+		cmd := exec.CommandContext(ctx, "sh", "-c", spec.Cmd)
+		out, err := cmd.Output()
+		cancel()
+		if err != nil {
+			utils.LogMessage("Command %q failed: %v", spec.Name, err)
+			continue
+		}
+		rel := strings.TrimPrefix(spec.OutputFile, "/")
+		dest := filepath.Join(repoDir, rel)
+		if err := os.MkdirAll(filepath.Dir(dest), 0o755); err != nil {
+			utils.LogMessage("Could not create directory for command %q output: %v", spec.Name, err)
+			continue
+		}
+		if err := os.WriteFile(dest, out, 0o644); err != nil {
+			utils.LogMessage("Could not write output of command %q: %v", spec.Name, err)
+			continue
+		}
+		utils.LogMessage("Captured output of %q into %s", spec.Name, rel)
+		repoFiles = append(repoFiles, rel)
+	}
+	return repoFiles
+}
+
+// resolvedRepoSubpath expands the repo_subpath template and rejects any
+// result that would escape the repo (absolute paths or ".." components).
+func resolvedRepoSubpath(cfg *config.Config) (string, error) {
+	if cfg.RepoSubpath == "" {
+		return "", nil
+	}
+	expanded, err := utils.ExpandPathTemplate(cfg.RepoSubpath)
+	if err != nil {
+		return "", fmt.Errorf("invalid repo_subpath template: %w", err)
+	}
+	cleaned := filepath.Clean(strings.Trim(expanded, "/"))
+	if cleaned == "." {
+		return "", nil
+	}
+	if cleaned == ".." || strings.HasPrefix(cleaned, "../") {
+		return "", fmt.Errorf("repo_subpath %q resolves outside the repo", expanded)
+	}
+	return cleaned, nil
+}
+
+// mirrorFiles copies every scanned file into the repo working tree and
+// returns the repo-relative paths that were mirrored successfully.
+func mirrorFiles(repoDir string, filesToInclude []string) []string {
+	var repoFiles []string
+	for _, src := range filesToInclude {
+		rel := strings.TrimPrefix(src, "/")
+		if err := mirrorFile(src, filepath.Join(repoDir, rel)); err != nil {
+			utils.LogMessage("Could not mirror %s: %v", src, err)
+			continue
+		}
+		repoFiles = append(repoFiles, rel)
+	}
+	return repoFiles
+}
+
+// mirrorFilesDeduped mirrors files like mirrorFiles but stores each unique
+// content only once: duplicates are recorded in the manifest pointing at the
+// canonical copy. Reports how much space dedup saved.
+func mirrorFilesDeduped(cfg *config.Config, repoDir string, filesToInclude []string) ([]string, error) {
+	hashes := hashFilesCached(cfg, filesToInclude)
+
+	m := manifest.New()
+	if cfg.HashAlgorithm != "" {
+		m.Algorithm = cfg.HashAlgorithm
+	}
+	byHash := make(map[string]string) // content hash -> canonical rel path
+	var repoFiles []string
+	var savedBytes int64
+	for _, src := range filesToInclude {
+		rel := strings.TrimPrefix(src, "/")
+		// Symlinks bypass dedup: the link itself is the content.
+		if info, err := os.Lstat(src); err == nil && info.Mode()&os.ModeSymlink != 0 && !followSymlinks {
+			target, err := os.Readlink(src)
+			if err != nil {
+				utils.LogMessage("Could not read symlink %s: %v", src, err)
+				continue
+			}
+			if err := mirrorFile(src, filepath.Join(repoDir, rel)); err != nil {
+				utils.LogMessage("Could not mirror %s: %v", src, err)
+				continue
+			}
+			m.Entries[rel] = manifest.Entry{Symlink: target}
+			repoFiles = append(repoFiles, rel)
+			continue
+		}
+		hash, ok := hashes[src]
+		if !ok {
+			utils.LogMessage("Could not hash %s; skipping", src)
+			continue
+		}
+		if canonical, ok := byHash[hash]; ok {
+			m.Entries[rel] = manifest.Entry{Hash: hash, DuplicateOf: canonical}
+			if info, err := os.Stat(src); err == nil {
+				savedBytes += info.Size()
+			}
+			continue
+		}
+		if err := mirrorFile(src, filepath.Join(repoDir, rel)); err != nil {
+			utils.LogMessage("Could not mirror %s: %v", src, err)
+			continue
+		}
+		byHash[hash] = rel
+		m.Entries[rel] = manifest.Entry{Hash: hash}
+		repoFiles = append(repoFiles, rel)
+	}
+	if err := m.Save(repoDir); err != nil {
+		return nil, err
+	}
+	repoFiles = append(repoFiles, manifest.FileName)
+	utils.LogMessage("Dedup saved %s across %d duplicate files", utils.HumanSize(savedBytes), len(m.Entries)-len(byHash))
+	return repoFiles, nil
+}
+
+// hashFilesCached returns the content hash of every file, rehashing only
+// files whose size or mtime changed since the last run (per the state file)
+// and fanning the rest out to the manifest's worker pool. The refreshed
+// cache is saved back to state for the next run.
+func hashFilesCached(cfg *config.Config, files []string) map[string]string {
+	st, err := config.LoadState()
+	if err != nil {
+		utils.LogMessage("Could not load state for hash cache: %v", err)
+		st = &config.State{}
+	}
+	if st.FileHashes == nil {
+		st.FileHashes = map[string]config.FileHash{}
+	}
+	// Cached hashes from a different algorithm would compare as changes
+	// against nothing; drop them wholesale and start over.
+	algorithm := cfg.HashAlgorithm
+	if algorithm == "" {
+		algorithm = manifest.DefaultAlgorithm
+	}
+	// Caches written before algorithms were selectable are sha256.
+	if st.HashAlgorithm == "" {
+		st.HashAlgorithm = manifest.DefaultAlgorithm
+	}
+	if st.HashAlgorithm != algorithm {
+		utils.LogMessage("Hash algorithm changed from %s to %s; invalidating hash cache", st.HashAlgorithm, algorithm)
+		st.FileHashes = map[string]config.FileHash{}
+		st.HashAlgorithm = algorithm
+	}
+
+	hashes := make(map[string]string, len(files))
+	infos := make(map[string]os.FileInfo, len(files))
+	var toHash []string
+	for _, src := range files {
+		info, err := os.Stat(src)
+		if err != nil {
+			continue
+		}
+		infos[src] = info
+		if fh, ok := st.FileHashes[src]; ok && fh.Size == info.Size() && fh.ModTimeUnix == info.ModTime().Unix() {
+			hashes[src] = fh.Hash
+			continue
+		}
+		toHash = append(toHash, src)
+	}
+	utils.LogMessage("Hashing %d of %d files (%d unchanged since last run)", len(toHash), len(files), len(hashes))
+
+	for src, hash := range manifest.HashFiles(toHash, algorithm, cfg.HashWorkers) {
+		hashes[src] = hash
+		info := infos[src]
+		st.FileHashes[src] = config.FileHash{Size: info.Size(), ModTimeUnix: info.ModTime().Unix(), Hash: hash}
+	}
+	if err := config.SaveState(st); err != nil {
+		utils.LogMessage("Could not save hash cache: %v", err)
+	}
+	return hashes
+}
+
+// mirrorFile copies src into the repo working tree, creating parent
+// directories as needed.
+func mirrorFile(src, dest string) error {
+	if err := os.MkdirAll(filepath.Dir(dest), 0o755); err != nil {
+		return err
+	}
+	// Symlinks are recorded as links (git symlink objects), not their
+	// targets' contents, unless follow_symlinks asks otherwise.
+	if !followSymlinks {
+		if info, err := os.Lstat(src); err == nil && info.Mode()&os.ModeSymlink != 0 {
+			target, err := os.Readlink(src)
+			if err != nil {
+				return err
+			}
+			if err := os.Remove(dest); err != nil && !os.IsNotExist(err) {
+				return err
+			}
+			return os.Symlink(target, dest)
+		}
+	}
+	in, err := os.Open(src)
+	if err != nil {
+		return err
+	}
+	defer in.Close()
+	info, err := in.Stat()
+	if err != nil {
+		return err
+	}
+	out, err := os.OpenFile(dest, os.O_CREATE|os.O_TRUNC|os.O_WRONLY, info.Mode().Perm())
+	if err != nil {
+		return err
+	}
+	defer out.Close()
+	_, err = io.CopyBuffer(out, in, make([]byte, copyBufferSize()))
+	return err
+}
+
+// defaultCopyBufferKB is the mirroring copy buffer when copy_buffer_kb is
+// unset; files stream through it rather than being slurped whole, so memory
+// stays flat no matter how large the backed-up files are.
+const defaultCopyBufferKB = 128
+
+// copyBufferBytes mirrors cfg.CopyBufferKB, set once per backup run.
+var copyBufferBytes int
+
+// copyBufferSize returns the copy buffer size in bytes.
+func copyBufferSize() int {
+	if copyBufferBytes > 0 {
+		return copyBufferBytes
+	}
+	return defaultCopyBufferKB * 1024
+}
+
+// runWhatIf previews what a frequency (or raw cron spec) resolves to and
+// when it would next fire. It modifies no config and no crontab.
+func runWhatIf(c *cli.Context) error {
+	frequency := c.Args().First()
+	if frequency == "" {
+		return fmt.Errorf("usage: what-if <frequency>")
+	}
+	spec, times, err := cron.PreviewSchedule(frequency, 5)
+	if err != nil {
+		return err
+	}
+	fmt.Printf("Frequency %q resolves to cron spec %q.\n", frequency, spec)
+	fmt.Println("Next 5 runs:")
+	for _, t := range times {
+		fmt.Println("  " + t.Format("2006-01-02 15:04 Mon"))
+	}
+	return nil
+}
@@ -0,0 +1,163 @@
+package manifest
+
+import (
+	"crypto/sha256"
+	"crypto/sha512"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"hash"
+	"io"
+	"os"
+	"path/filepath"
+	"runtime"
+	"sync"
+
+	"golang.org/x/crypto/blake2b"
+)
+
+// FileName is the manifest's path inside the backup repo.
+const FileName = ".giterdone-manifest.json"
+
+// DefaultAlgorithm is the hash used when hash_algorithm is unset.
+const DefaultAlgorithm = "sha256"
+
+// Entry records what giterdone knows about one backed-up file.
+type Entry struct {
+	Hash string `json:"hash"`
+	// DuplicateOf, when set, names the repo-relative path holding this
+	// file's content; the file itself was not stored separately.
+	DuplicateOf string `json:"duplicate_of,omitempty"`
+	// Symlink, when set, marks the entry as a symbolic link and records its
+	// target; restore recreates the link rather than copying contents.
+	Symlink string `json:"symlink,omitempty"`
+}
+
+// Manifest maps repo-relative paths to their entries.
+type Manifest struct {
+	// Algorithm names the hash used for every entry, so verify and restore
+	// check integrity with the algorithm the backup was written with even
+	// after the config changes.
+	Algorithm string           `json:"algorithm,omitempty"`
+	Entries   map[string]Entry `json:"entries"`
+}
+
+// New returns an empty manifest.
+func New() *Manifest {
+	return &Manifest{Algorithm: DefaultAlgorithm, Entries: map[string]Entry{}}
+}
+
+// Load reads the manifest from the repo. A missing manifest yields an empty
+// one.
+func Load(repoDir string) (*Manifest, error) {
+	data, err := os.ReadFile(filepath.Join(repoDir, FileName))
+	if os.IsNotExist(err) {
+		return New(), nil
+	} else if err != nil {
+		return nil, err
+	}
+	var m Manifest
+	if err := json.Unmarshal(data, &m); err != nil {
+		return nil, err
+	}
+	if m.Entries == nil {
+		m.Entries = map[string]Entry{}
+	}
+	// Manifests written before algorithms were selectable are sha256.
+	if m.Algorithm == "" {
+		m.Algorithm = DefaultAlgorithm
+	}
+	return &m, nil
+}
+
+// Save writes the manifest into the repo.
+func (m *Manifest) Save(repoDir string) error {
+	data, err := json.MarshalIndent(m, "", "  ")
+	if err != nil {
+		return err
+	}
+	return os.WriteFile(filepath.Join(repoDir, FileName), data, 0o644)
+}
+
+// HashFiles hashes every path concurrently with a bounded worker pool of
+// the given size (0 or less means one worker per CPU), since hashing
+// thousands of files serially dominates runs on frequent schedules. The
+// result maps path to hex hash; unreadable files are simply absent, for the
+// caller to skip or report.
+func HashFiles(paths []string, algorithm string, workers int) map[string]string {
+	if workers <= 0 {
+		workers = runtime.NumCPU()
+	}
+	type result struct{ path, hash string }
+	jobs := make(chan string)
+	results := make(chan result)
+	var wg sync.WaitGroup
+	for i := 0; i < workers; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for p := range jobs {
+				if h, err := HashFileWith(p, algorithm); err == nil {
+					results <- result{p, h}
+				}
+			}
+		}()
+	}
+	go func() {
+		for _, p := range paths {
+			jobs <- p
+		}
+		close(jobs)
+	}()
+	go func() {
+		wg.Wait()
+		close(results)
+	}()
+	out := make(map[string]string, len(paths))
+	for r := range results {
+		out[r.path] = r.hash
+	}
+	return out
+}
+
+// HashFile returns the hex SHA-256 of a file's contents.
+func HashFile(path string) (string, error) {
+	return HashFileWith(path, DefaultAlgorithm)
+}
+
+// HashFileWith returns the hex digest of a file's contents under the named
+// algorithm.
+func HashFileWith(path, algorithm string) (string, error) {
+	h, err := NewHash(algorithm)
+	if err != nil {
+		return "", err
+	}
+	f, err := os.Open(path)
+	if err != nil {
+		return "", err
+	}
+	defer f.Close()
+	if _, err := io.Copy(h, f); err != nil {
+		return "", err
+	}
+	return hex.EncodeToString(h.Sum(nil)), nil
+}
+
+// NewHash returns a fresh hasher for the named algorithm; an empty name
+// means DefaultAlgorithm. Callers validating config can discard the hasher.
+func NewHash(algorithm string) (hash.Hash, error) {
+	switch algorithm {
+	case "", "sha256":
+		return sha256.New(), nil
+	case "sha512":
+		return sha512.New(), nil
+	case "blake2b":
+		h, err := blake2b.New256(nil)
+		if err != nil {
+			return nil, err
+		}
+		return h, nil
+	default:
+		return nil, fmt.Errorf("unsupported hash_algorithm %q (expected sha256, sha512 or blake2b)", algorithm)
+	}
+}
@@ -0,0 +1,93 @@
+package manifest
+
+import (
+	"os"
+	"path/filepath"
+	"strconv"
+	"testing"
+)
+
+func TestHashFilesMatchesHashFile(t *testing.T) {
+	dir := t.TempDir()
+	var paths []string
+	for i := 0; i < 20; i++ {
+		p := filepath.Join(dir, "file-"+strconv.Itoa(i))
+		if err := os.WriteFile(p, []byte(strconv.Itoa(i)), 0o644); err != nil {
+			t.Fatal(err)
+		}
+		paths = append(paths, p)
+	}
+	// An unreadable path must be absent, not an error.
+	paths = append(paths, filepath.Join(dir, "missing"))
+
+	got := HashFiles(paths, DefaultAlgorithm, 4)
+	if len(got) != 20 {
+		t.Fatalf("expected 20 hashes, got %d", len(got))
+	}
+	for _, p := range paths[:20] {
+		want, err := HashFile(p)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if got[p] != want {
+			t.Errorf("HashFiles(%s) = %s, want %s", p, got[p], want)
+		}
+	}
+}
+
+// benchmarkTree lays out a tree of identical-size files once per benchmark
+// run so serial and pooled hashing see the same workload.
+func benchmarkTree(b *testing.B) []string {
+	dir := b.TempDir()
+	data := make([]byte, 64*1024)
+	var paths []string
+	for i := 0; i < 200; i++ {
+		p := filepath.Join(dir, "file-"+strconv.Itoa(i))
+		if err := os.WriteFile(p, data, 0o644); err != nil {
+			b.Fatal(err)
+		}
+		paths = append(paths, p)
+	}
+	return paths
+}
+
+func BenchmarkHashFilesSerial(b *testing.B) {
+	paths := benchmarkTree(b)
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		for _, p := range paths {
+			if _, err := HashFile(p); err != nil {
+				b.Fatal(err)
+			}
+		}
+	}
+}
+
+func BenchmarkHashFilesPooled(b *testing.B) {
+	paths := benchmarkTree(b)
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		HashFiles(paths, DefaultAlgorithm, 0)
+	}
+}
+
+func TestHashFileWithAlgorithms(t *testing.T) {
+	p := filepath.Join(t.TempDir(), "f")
+	if err := os.WriteFile(p, []byte("giterdone"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	seen := map[string]bool{}
+	for _, alg := range []string{"sha256", "sha512", "blake2b"} {
+		h, err := HashFileWith(p, alg)
+		if err != nil {
+			t.Fatalf("HashFileWith(%s): %v", alg, err)
+		}
+		if seen[h] {
+			t.Errorf("algorithm %s produced a digest already seen", alg)
+		}
+		seen[h] = true
+	}
+	if _, err := HashFileWith(p, "md5"); err == nil {
+		t.Error("expected an error for an unsupported algorithm")
+	}
+}
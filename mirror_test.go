@@ -0,0 +1,64 @@
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"strconv"
+	"testing"
+	"time"
+
+	"github.com/cdmerchant13/giterdone/config"
+)
+
+func TestResolvedRepoSubpath(t *testing.T) {
+	year := strconv.Itoa(time.Now().Year())
+	cases := []struct {
+		subpath string
+		want    string
+		wantErr bool
+	}{
+		{"", "", false},
+		{"snapshots", "snapshots", false},
+		{"snapshots/{{.Year}}", "snapshots/" + year, false},
+		{"/snapshots/", "snapshots", false},
+		{"a/../b", "b", false},
+		{"..", "", true},
+		{"../escape", "", true},
+		{"a/../../escape", "", true},
+		{"{{.Bogus}}", "", true},
+	}
+	for _, tc := range cases {
+		got, err := resolvedRepoSubpath(&config.Config{RepoSubpath: tc.subpath})
+		if tc.wantErr {
+			if err == nil {
+				t.Errorf("resolvedRepoSubpath(%q) = %q, want error", tc.subpath, got)
+			}
+			continue
+		}
+		if err != nil {
+			t.Errorf("resolvedRepoSubpath(%q): %v", tc.subpath, err)
+		} else if got != tc.want {
+			t.Errorf("resolvedRepoSubpath(%q) = %q, want %q", tc.subpath, got, tc.want)
+		}
+	}
+}
+
+// BenchmarkMirrorFile copies a large file repeatedly and reports allocations:
+// streaming through the fixed-size copy buffer keeps memory flat regardless
+// of file size, where a whole-file read would allocate the full 64MB per
+// copy.
+func BenchmarkMirrorFile(b *testing.B) {
+	dir := b.TempDir()
+	src := filepath.Join(dir, "large.bin")
+	if err := os.WriteFile(src, make([]byte, 64*1024*1024), 0o644); err != nil {
+		b.Fatal(err)
+	}
+	dest := filepath.Join(dir, "mirror", "large.bin")
+	b.ReportAllocs()
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		if err := mirrorFile(src, dest); err != nil {
+			b.Fatal(err)
+		}
+	}
+}
@@ -0,0 +1,68 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"os"
+	"time"
+
+	"github.com/cdmerchant13/giterdone/config"
+	"github.com/cdmerchant13/giterdone/utils"
+)
+
+// notifyResult posts the outcome of a backup run to the configured webhook.
+// Failures are always reported; successes only when NotifyOnSuccess is set.
+// Delivery is strictly best-effort: a slow or broken webhook is logged and
+// never changes the backup's own result, and the call is bounded by a short
+// timeout so a scheduled run cannot hang on it.
+func notifyResult(cfg *config.Config, runErr error) {
+	if cfg.NotifyWebhookURL == "" {
+		return
+	}
+	if runErr == nil && !cfg.NotifyOnSuccess {
+		return
+	}
+	status := "success"
+	errText := ""
+	if runErr != nil {
+		status = "failed"
+		errText = runErr.Error()
+	}
+	hostname, _ := os.Hostname()
+	now := time.Now().Format(time.RFC3339)
+
+	var payload interface{}
+	switch cfg.NotifyFormat {
+	case "slack":
+		text := fmt.Sprintf("giterdone backup on %s: %s", hostname, status)
+		if errText != "" {
+			text += " -- " + errText
+		}
+		payload = struct {
+			Text string `json:"text"`
+		}{text}
+	default:
+		payload = struct {
+			Status string `json:"status"`
+			Error  string `json:"error,omitempty"`
+			Host   string `json:"host"`
+			Time   string `json:"time"`
+		}{status, errText, hostname, now}
+	}
+	body, err := json.Marshal(payload)
+	if err != nil {
+		return
+	}
+	client := &http.Client{Timeout: 10 * time.Second}
+	resp, err := client.Post(cfg.NotifyWebhookURL, "application/json", bytes.NewReader(body))
+	if err != nil {
+		utils.LogMessage("Warning: could not deliver webhook notification: %v", err)
+		return
+	}
+	resp.Body.Close()
+	if resp.StatusCode >= 300 {
+		utils.LogMessage("Warning: webhook notification returned HTTP %d", resp.StatusCode)
+	}
+}
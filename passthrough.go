@@ -0,0 +1,79 @@
+package main
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"os"
+	"os/exec"
+
+	"github.com/urfave/cli/v2"
+
+	"github.com/cdmerchant13/giterdone/config"
+	"github.com/cdmerchant13/giterdone/git"
+	"github.com/cdmerchant13/giterdone/utils"
+)
+
+// runGitPassthrough executes an arbitrary git command against the owned
+// backup repo -- the escape hatch for maintenance giterdone doesn't natively
+// support -- with the working directory and credentials set up as a backup
+// run would have them, and any PAT leakage scrubbed from the output.
+func runGitPassthrough(c *cli.Context) error {
+	configDir, err := config.GetConfigDir()
+	if err != nil {
+		return err
+	}
+	if err := utils.InitLogger(configDir, false); err != nil {
+		return err
+	}
+	args := c.Args().Slice()
+	if len(args) > 0 && args[0] == "--" {
+		args = args[1:]
+	}
+	if len(args) == 0 {
+		return fmt.Errorf("usage: git -- <args...>")
+	}
+
+	cfg, err := config.LoadConfig()
+	if err != nil {
+		return err
+	}
+	repoDir, err := config.GetRepoDir()
+	if err != nil {
+		return err
+	}
+	if !git.IsGitRepo(repoDir) {
+		return fmt.Errorf("no backup repo at %s yet; run a backup first", repoDir)
+	}
+	if err := git.SetRemoteOrigin(repoDir, cfg.AuthenticatedRepoURL()); err != nil {
+		return err
+	}
+
+	utils.LogMessage("Running passthrough: git %v", args)
+	cmd := exec.Command("git", args...)
+	cmd.Dir = repoDir
+	cmd.Stdin = os.Stdin
+	cmd.Stdout = &redactingWriter{w: os.Stdout, secret: cfg.PAT}
+	cmd.Stderr = &redactingWriter{w: os.Stderr, secret: cfg.PAT}
+	return cmd.Run()
+}
+
+// redactingWriter streams output while replacing the secret with a
+// placeholder, so commands like `git remote -v` cannot leak the PAT. A
+// secret split across two writes can slip through; git emits remote URLs on
+// single lines, so in practice this does not occur.
+type redactingWriter struct {
+	w      io.Writer
+	secret string
+}
+
+func (rw *redactingWriter) Write(p []byte) (int, error) {
+	if rw.secret != "" {
+		redacted := bytes.ReplaceAll(p, []byte(rw.secret), []byte("***"))
+		if _, err := rw.w.Write(redacted); err != nil {
+			return 0, err
+		}
+		return len(p), nil
+	}
+	return rw.w.Write(p)
+}
@@ -0,0 +1,109 @@
+package main
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/cdmerchant13/giterdone/config"
+	"github.com/cdmerchant13/giterdone/git"
+	"github.com/cdmerchant13/giterdone/utils"
+)
+
+// profileResult records one profile's outcome for the consolidated summary.
+type profileResult struct {
+	profile string
+	summary backupSummary
+	err     error
+}
+
+// summaryCollector aggregates per-profile results so a `--profile all` run
+// produces one consolidated summary instead of disjoint logs.
+type summaryCollector struct {
+	results []profileResult
+}
+
+// add records one profile's outcome.
+func (sc *summaryCollector) add(profile string, summary backupSummary, err error) {
+	sc.results = append(sc.results, profileResult{profile: profile, summary: summary, err: err})
+}
+
+// report prints and logs the consolidated summary and returns an error when
+// any profile failed, so the process exits nonzero as a unit.
+func (sc *summaryCollector) report() error {
+	fmt.Println("Run summary:")
+	var failed []string
+	for _, r := range sc.results {
+		status := "ok"
+		if r.err != nil {
+			status = "FAILED: " + r.err.Error()
+			failed = append(failed, r.profile)
+		}
+		line := fmt.Sprintf("  %-12s %d files scanned, committed=%v, pushed=%v — %s",
+			r.profile, r.summary.filesScanned, r.summary.committed, r.summary.pushed, status)
+		fmt.Println(line)
+		utils.LogMessage("Profile %s: %s", r.profile, status)
+	}
+	if len(failed) > 0 {
+		return fmt.Errorf("%d of %d profiles failed: %s", len(failed), len(sc.results), strings.Join(failed, ", "))
+	}
+	return nil
+}
+
+// runAllProfiles backs up the default profile plus every named profile in
+// one invocation, reporting into a single summary collector.
+func runAllProfiles(dryRun string, onlyIfOnline bool) error {
+	named, err := config.ListProfiles()
+	if err != nil {
+		return err
+	}
+	profiles := append([]string{""}, named...)
+
+	var collector summaryCollector
+	for _, profile := range profiles {
+		config.SetActiveProfile(profile)
+		label := profile
+		if label == "" {
+			label = "default"
+		}
+		utils.LogMessage("Running profile %s", label)
+		lastRun = backupSummary{}
+		err := runProfileBackup(dryRun, onlyIfOnline)
+		collector.add(label, lastRun, err)
+	}
+	config.SetActiveProfile("")
+	return collector.report()
+}
+
+// runProfileBackup loads the active profile's config and runs one backup
+// with the shared pre-flight steps (run-scoped setup, host paths, paths
+// file, offline check, PAT expiry).
+func runProfileBackup(dryRun string, onlyIfOnline bool) error {
+	cfg, err := config.LoadConfig()
+	if err != nil {
+		return err
+	}
+	if err := applyRunConfig(cfg); err != nil {
+		return err
+	}
+	if err := cfg.ApplyHostPaths(); err != nil {
+		utils.LogMessage("Could not resolve hostname for paths_by_host: %v", err)
+	}
+	if err := cfg.MergePathsFile(); err != nil {
+		utils.LogMessage("Could not read ~/.giterdone-paths: %v", err)
+	}
+	if onlyIfOnline || cfg.OnlyIfOnline {
+		if !git.IsRemoteReachable(cfg.AuthenticatedRepoURL()) {
+			utils.LogMessage("Offline, skipping backup")
+			fmt.Println("Offline, skipping backup.")
+			return nil
+		}
+	}
+	if err := checkPATExpiry(cfg); err != nil {
+		return err
+	}
+	err = runBackup(cfg, dryRun)
+	if dryRun == "" {
+		notifyResult(cfg, err)
+	}
+	return err
+}
@@ -0,0 +1,75 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/cdmerchant13/giterdone/config"
+	"github.com/cdmerchant13/giterdone/git"
+	"github.com/cdmerchant13/giterdone/utils"
+)
+
+// ensureRemoteRepo creates the configured repo via the provider API when it
+// does not exist yet, so new users don't have to create it by hand before
+// the first push. Only runs when CreateRepoIfMissing is set.
+func ensureRemoteRepo(cfg *config.Config) error {
+	if !cfg.CreateRepoIfMissing {
+		return nil
+	}
+	if git.IsRemoteReachable(cfg.AuthenticatedRepoURL()) {
+		return nil
+	}
+	if cfg.EffectiveProvider() != "github" {
+		utils.LogMessage("create_repo_if_missing is only supported for GitHub remotes; skipping")
+		return nil
+	}
+	if cfg.PAT == "" {
+		return fmt.Errorf("create_repo_if_missing requires a PAT to call the GitHub API")
+	}
+
+	repoPath := strings.TrimSuffix(strings.TrimPrefix(cfg.RepoURL, "https://github.com/"), ".git")
+	parts := strings.SplitN(repoPath, "/", 2)
+	if len(parts) != 2 {
+		return fmt.Errorf("could not parse owner/repo from %s", cfg.RepoURL)
+	}
+	name := parts[1]
+
+	private := cfg.RepoVisibility != "public"
+	body, _ := json.Marshal(map[string]interface{}{
+		"name":    name,
+		"private": private,
+	})
+	req, err := http.NewRequest("POST", "https://api.github.com/user/repos", bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Authorization", "Bearer "+cfg.PAT)
+	req.Header.Set("Accept", "application/vnd.github+json")
+	client := &http.Client{Timeout: 15 * time.Second}
+	resp, err := client.Do(req)
+	if err != nil {
+		return fmt.Errorf("could not create repo via GitHub API: %w", err)
+	}
+	defer resp.Body.Close()
+
+	switch resp.StatusCode {
+	case http.StatusCreated:
+		visibility := "private"
+		if !private {
+			visibility = "public"
+		}
+		utils.LogMessage("Created %s repo %s on GitHub", visibility, repoPath)
+		return nil
+	case http.StatusUnprocessableEntity:
+		// Already exists (e.g. unreachable for another reason); proceed and
+		// let the normal git error surface if something is really wrong.
+		utils.LogMessage("Repo %s already exists on GitHub", repoPath)
+		return nil
+	default:
+		return fmt.Errorf("GitHub API repo creation failed with status %s (check PAT scopes)", resp.Status)
+	}
+}
@@ -0,0 +1,109 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/cdmerchant13/giterdone/config"
+	"github.com/cdmerchant13/giterdone/cron"
+	"github.com/cdmerchant13/giterdone/scanner"
+	"github.com/cdmerchant13/giterdone/utils"
+)
+
+// dryRunReportPath, when set by --dry-run-report, is where the dry run
+// writes its structured report; set once per invocation.
+var dryRunReportPath string
+
+// dryRunReport is the shareable artifact a dry run can produce, complete
+// enough to review a proposed backup without access to the machine.
+type dryRunReport struct {
+	GeneratedAt   time.Time           `json:"generated_at"`
+	Hostname      string              `json:"hostname"`
+	RepoURL       string              `json:"repo_url"`
+	Frequency     string              `json:"frequency"`
+	CronSpec      string              `json:"cron_spec,omitempty"`
+	NextRun       string              `json:"next_run,omitempty"`
+	CommitMessage string              `json:"commit_message"`
+	ScannedFiles  []string            `json:"scanned_files"`
+	Excluded      map[string][]string `json:"excluded"`
+}
+
+// writeDryRunReport writes the dry-run report to dryRunReportPath, as JSON
+// for .json paths and markdown otherwise.
+func writeDryRunReport(cfg *config.Config, files []string) error {
+	hostname, _ := os.Hostname()
+	report := dryRunReport{
+		GeneratedAt:  time.Now(),
+		Hostname:     hostname,
+		RepoURL:      cfg.SanitizedRepoURL(),
+		Frequency:    cfg.Frequency,
+		ScannedFiles: files,
+	}
+
+	tmpl, err := cfg.CommitTemplate()
+	if err == nil {
+		report.CommitMessage, _ = utils.GenerateCommitMessage(tmpl, len(files))
+	}
+	if spec, times, err := cron.PreviewSchedule(cfg.Frequency, 1); err == nil {
+		report.CronSpec = spec
+		report.NextRun = times[0].Format("2006-01-02 15:04")
+	}
+	excluded, err := scanner.ListExcluded(cfg)
+	if err != nil {
+		utils.LogMessage("Could not list exclusions for report: %v", err)
+		excluded = map[string][]string{}
+	}
+	report.Excluded = excluded
+
+	var data []byte
+	if strings.HasSuffix(dryRunReportPath, ".json") {
+		data, err = json.MarshalIndent(&report, "", "  ")
+		if err != nil {
+			return err
+		}
+	} else {
+		data = []byte(report.markdown())
+	}
+	if err := os.WriteFile(dryRunReportPath, data, 0o644); err != nil {
+		return fmt.Errorf("could not write dry-run report: %w", err)
+	}
+	fmt.Printf("Dry-run report written to %s.\n", dryRunReportPath)
+	return nil
+}
+
+// markdown renders the report as a human-readable document.
+func (r *dryRunReport) markdown() string {
+	var sb strings.Builder
+	fmt.Fprintf(&sb, "# Giterdone dry-run report\n\n")
+	fmt.Fprintf(&sb, "- Generated: %s\n", r.GeneratedAt.Format("2006-01-02 15:04:05"))
+	fmt.Fprintf(&sb, "- Host: %s\n", r.Hostname)
+	fmt.Fprintf(&sb, "- Repo: %s\n", r.RepoURL)
+	if r.CronSpec != "" {
+		fmt.Fprintf(&sb, "- Schedule: %s (`%s`), next run %s\n", r.Frequency, r.CronSpec, r.NextRun)
+	} else {
+		fmt.Fprintf(&sb, "- Schedule: %s\n", r.Frequency)
+	}
+	fmt.Fprintf(&sb, "- Commit message: %s\n", r.CommitMessage)
+
+	fmt.Fprintf(&sb, "\n## Files to back up (%d)\n\n", len(r.ScannedFiles))
+	for _, f := range r.ScannedFiles {
+		fmt.Fprintf(&sb, "- %s\n", f)
+	}
+
+	var reasons []string
+	for reason := range r.Excluded {
+		reasons = append(reasons, reason)
+	}
+	sort.Strings(reasons)
+	for _, reason := range reasons {
+		fmt.Fprintf(&sb, "\n## Excluded: %s (%d)\n\n", reason, len(r.Excluded[reason]))
+		for _, f := range r.Excluded[reason] {
+			fmt.Fprintf(&sb, "- %s\n", f)
+		}
+	}
+	return sb.String()
+}
@@ -0,0 +1,179 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/urfave/cli/v2"
+
+	"github.com/cdmerchant13/giterdone/config"
+	"github.com/cdmerchant13/giterdone/git"
+	"github.com/cdmerchant13/giterdone/manifest"
+	"github.com/cdmerchant13/giterdone/utils"
+)
+
+// runRestore copies backed-up files from the repo back to their original
+// locations. With --only it restores just the named paths, with --from it
+// restores file contents as of a historical commit, and with --dry-run it
+// only reports what would be written. Existing files are overwritten only
+// after a confirmation (or with --assume-yes).
+func runRestore(c *cli.Context) error {
+	assumeYes = c.Bool("assume-yes")
+	dryRun := c.Bool("dry-run")
+	configDir, err := config.GetConfigDir()
+	if err != nil {
+		return err
+	}
+	if err := utils.InitLogger(configDir, c.Bool("verbose")); err != nil {
+		return err
+	}
+	cfg, err := config.LoadConfig()
+	if err != nil {
+		return err
+	}
+	backend, err := storageBackendFor(cfg)
+	if err != nil {
+		return err
+	}
+	if backend != nil {
+		if dryRun {
+			return fmt.Errorf("--dry-run restore is not supported with an archive storage backend")
+		}
+		return runArchiveRestore(cfg, c.StringSlice("only"), backend)
+	}
+
+	repoDir, err := config.GetRepoDir()
+	if err != nil {
+		return err
+	}
+
+	if !git.IsGitRepo(repoDir) {
+		if err := git.CloneRepo(cfg.AuthenticatedRepoURL(), repoDir); err != nil {
+			return err
+		}
+	} else if err := git.Pull(repoDir); err != nil {
+		return err
+	}
+
+	// With repo_subpath set, everything in the repo is nested under the
+	// subpath; tracked paths are stripped back to source-absolute paths
+	// before restoring.
+	subpath, err := resolvedRepoSubpath(cfg)
+	if err != nil {
+		return err
+	}
+
+	var relPaths []string
+	if only := c.StringSlice("only"); len(only) > 0 {
+		for _, p := range only {
+			relPaths = append(relPaths, filepath.Join(subpath, strings.TrimPrefix(p, "/")))
+		}
+	} else {
+		relPaths, err = git.LsFiles(repoDir)
+		if err != nil {
+			return err
+		}
+	}
+
+	m, err := manifest.Load(repoDir)
+	if err != nil {
+		utils.LogMessage("Could not load manifest: %v", err)
+		m = manifest.New()
+	}
+
+	from := c.String("from")
+	restored := 0
+	for _, rel := range relPaths {
+		if rel == ".gitignore" || rel == manifest.FileName || rel == xattrManifestName {
+			continue
+		}
+		if subpath != "" {
+			if !strings.HasPrefix(rel, subpath+"/") {
+				// Repo-root bookkeeping or files outside the snapshot
+				// subpath; nothing to restore.
+				continue
+			}
+			rel = strings.TrimPrefix(rel, subpath+"/")
+		}
+		dest := "/" + rel
+		_, statErr := os.Lstat(dest)
+		exists := statErr == nil
+		if dryRun {
+			if exists {
+				fmt.Printf("would overwrite %s\n", dest)
+			} else {
+				fmt.Printf("would create %s\n", dest)
+			}
+			restored++
+			continue
+		}
+		if exists {
+			ok, err := confirm("Overwrite " + dest)
+			if err != nil {
+				return err
+			}
+			if !ok {
+				utils.LogMessage("Skipping %s: overwrite declined", dest)
+				continue
+			}
+		}
+		// Symlinks are recreated as links, never by copying the target.
+		if entry, ok := m.Entries[rel]; ok && entry.Symlink != "" {
+			if err := restoreSymlink(entry.Symlink, dest); err != nil {
+				utils.LogMessage("Could not restore symlink %s: %v", dest, err)
+				continue
+			}
+			restored++
+			continue
+		}
+		// Deduplicated files were not stored themselves; read the
+		// canonical copy the manifest points at.
+		src := rel
+		if entry, ok := m.Entries[rel]; ok && entry.DuplicateOf != "" {
+			src = entry.DuplicateOf
+		}
+		if err := restoreFile(repoDir, filepath.Join(subpath, src), dest, from); err != nil {
+			utils.LogMessage("Could not restore %s: %v", dest, err)
+			continue
+		}
+		restored++
+	}
+	if dryRun {
+		fmt.Printf("Dry run: %d files would be restored.\n", restored)
+		return nil
+	}
+	if cfg.PreserveXattrs {
+		applyXattrs(repoDir)
+	}
+	fmt.Printf("Restored %d files.\n", restored)
+	return nil
+}
+
+// restoreFile writes one backed-up file to its original location, reading
+// from the given commit when set and from the work tree otherwise.
+func restoreFile(repoDir, rel, dest, commit string) error {
+	if err := os.MkdirAll(filepath.Dir(dest), 0o755); err != nil {
+		return err
+	}
+	if commit != "" {
+		data, err := git.ShowFile(repoDir, commit, rel)
+		if err != nil {
+			return err
+		}
+		return os.WriteFile(dest, data, 0o644)
+	}
+	return mirrorFile(filepath.Join(repoDir, rel), dest)
+}
+
+// restoreSymlink recreates a backed-up symlink pointing at target.
+func restoreSymlink(target, dest string) error {
+	if err := os.MkdirAll(filepath.Dir(dest), 0o755); err != nil {
+		return err
+	}
+	if err := os.Remove(dest); err != nil && !os.IsNotExist(err) {
+		return err
+	}
+	return os.Symlink(target, dest)
+}
@@ -0,0 +1,79 @@
+package main
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/urfave/cli/v2"
+
+	"github.com/cdmerchant13/giterdone/config"
+	"github.com/cdmerchant13/giterdone/git"
+	"github.com/cdmerchant13/giterdone/utils"
+)
+
+// runRewriteHistoryRemove purges a file from every commit in the backup
+// history and force-pushes the result -- the recovery tool for a secret
+// committed across many backups, where deleting it going forward is not
+// enough. It rewrites shared history, so it demands --force plus an
+// interactive confirmation.
+func runRewriteHistoryRemove(c *cli.Context) error {
+	configDir, err := config.GetConfigDir()
+	if err != nil {
+		return err
+	}
+	if err := utils.InitLogger(configDir, true); err != nil {
+		return err
+	}
+	path := c.Args().First()
+	if path == "" {
+		return fmt.Errorf("usage: rewrite-history-remove <path>")
+	}
+	if !c.Bool("force") {
+		return fmt.Errorf("this rewrites ALL history and force-pushes, breaking every other clone of the backup repo; re-run with --force if you are sure")
+	}
+
+	cfg, err := config.LoadConfig()
+	if err != nil {
+		return err
+	}
+	if cfg.IsSafeMode() {
+		return fmt.Errorf("rewrite-history-remove rewrites history and force-pushes; set safe_mode to false to allow it")
+	}
+	repoDir, err := config.GetRepoDir()
+	if err != nil {
+		return err
+	}
+	if !git.IsGitRepo(repoDir) {
+		if err := git.CloneRepo(cfg.AuthenticatedRepoURL(), repoDir); err != nil {
+			return err
+		}
+	}
+
+	rel := strings.TrimPrefix(path, "/")
+	fmt.Printf("WARNING: about to remove %s from every commit and force-push.\n", rel)
+	fmt.Println("Other machines sharing this backup repo must re-clone afterwards.")
+	ok, err := confirm("Rewrite history and force-push")
+	if err != nil {
+		return err
+	}
+	if !ok {
+		return fmt.Errorf("aborted")
+	}
+
+	if err := git.FilterRepoRemovePath(repoDir, rel); err != nil {
+		return err
+	}
+	// git-filter-repo strips the origin remote as a safety measure; put it
+	// back before pushing.
+	if err := git.SetRemoteOrigin(repoDir, cfg.AuthenticatedRepoURL()); err != nil {
+		if err := git.AddRemote(repoDir, "origin", cfg.AuthenticatedRepoURL()); err != nil {
+			return err
+		}
+	}
+	if err := git.PushForce(repoDir); err != nil {
+		return err
+	}
+	fmt.Printf("Removed %s from all history and force-pushed.\n", rel)
+	utils.LogMessage("Purged %s from all history", rel)
+	return nil
+}
@@ -0,0 +1,687 @@
+package scanner
+
+import (
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/cdmerchant13/giterdone/config"
+	"github.com/cdmerchant13/giterdone/utils"
+)
+
+// maxFileSize is the default cutoff above which files are excluded from
+// backups, overridable via max_file_size_bytes in the config.
+const maxFileSize = 100 * 1024 * 1024 // 100MB
+
+// excludePatterns are file and directory patterns that are never backed up.
+var excludePatterns = []string{
+	"*.log",
+	"*.tmp",
+	"*.swp",
+	"*.iso",
+	"*.img",
+	"*.o",
+	"*.so",
+	"node_modules",
+	".cache",
+	".git",
+	"__pycache__",
+}
+
+// pathResult holds the outcome of scanning a single include path.
+type pathResult struct {
+	root     string
+	files    []string
+	excludes []string
+	// patternHits records which built-in patterns actually matched
+	// something, so the generated .gitignore only lists patterns that are
+	// needed rather than every junk pattern we know about.
+	patternHits map[string]bool
+	duration    time.Duration
+	err         error
+}
+
+// ScanFiles walks every configured include path and returns the files to
+// back up plus the patterns (and oversized paths) to exclude. Each top-level
+// include path is scanned in its own goroutine so a slow or unreadable path
+// does not block the others; per-path failures are reported in the summary
+// without aborting the whole scan.
+func ScanFiles(cfg *config.Config) ([]string, []string, error) {
+	m := newMatcher(cfg)
+	includePaths := ExpandIncludePaths(cfg.IncludePaths)
+	results := make([]pathResult, len(includePaths))
+	var wg sync.WaitGroup
+	for i, root := range includePaths {
+		wg.Add(1)
+		go func(i int, root string) {
+			defer wg.Done()
+			results[i] = scanPath(root, cfg, m)
+		}(i, root)
+	}
+	wg.Wait()
+
+	var filesToInclude []string
+	var patternsToExclude []string
+	hits := map[string]bool{}
+	failed := 0
+	for _, res := range results {
+		if res.err != nil {
+			failed++
+			utils.LogMessage("Scan of %s failed after %s: %v", res.root, res.duration.Round(time.Millisecond), res.err)
+			continue
+		}
+		utils.LogMessage("Scanned %s: %d files in %s", res.root, len(res.files), res.duration.Round(time.Millisecond))
+		filesToInclude = append(filesToInclude, res.files...)
+		patternsToExclude = append(patternsToExclude, res.excludes...)
+		for p := range res.patternHits {
+			hits[p] = true
+		}
+	}
+	// Only emit built-in and user patterns that actually matched something;
+	// git does not track empty or skipped directories, so unmatched patterns
+	// would be pure noise in the generated .gitignore.
+	for _, p := range excludePatterns {
+		if hits[p] {
+			patternsToExclude = append(patternsToExclude, p)
+		}
+	}
+	for _, p := range cfg.ExcludePatterns {
+		if hits[p] {
+			patternsToExclude = append(patternsToExclude, p)
+		}
+	}
+	if failed == len(includePaths) && failed > 0 {
+		return nil, nil, fmt.Errorf("all %d include paths failed to scan", failed)
+	}
+	// Overlapping include paths (a dir plus a file inside it) scan the same
+	// file twice; collapse duplicates and sort so downstream logs and git
+	// invocations are deterministic.
+	filesToInclude = dedupePatterns(filesToInclude)
+	patternsToExclude = dedupePatterns(patternsToExclude)
+	if cfg.UseGitCheckIgnore {
+		filtered, err := filterWithGitCheckIgnore(filesToInclude, patternsToExclude)
+		if err != nil {
+			utils.LogMessage("git check-ignore unavailable (%v); falling back to built-in matching", err)
+		} else {
+			filesToInclude = filtered
+		}
+	}
+	return filesToInclude, patternsToExclude, nil
+}
+
+// ExpandIncludePaths resolves ~/$VAR references (via config.ExpandUserPath),
+// shell-style globs, and recursive ** patterns in the configured include
+// paths into concrete paths, so broad rules like ~/.config/*/settings.json
+// work without enumerating every file.
+// Literal paths pass through untouched, a pattern matching nothing warns
+// rather than failing the run, and duplicates collapse.
+func ExpandIncludePaths(paths []string) []string {
+	var out []string
+	seen := map[string]bool{}
+	add := func(p string) {
+		if !seen[p] {
+			seen[p] = true
+			out = append(out, p)
+		}
+	}
+	for _, raw := range paths {
+		p := config.ExpandUserPath(raw)
+		if p != raw {
+			utils.LogMessage("Resolved include path %s -> %s", raw, p)
+		}
+		if !strings.ContainsAny(p, "*?[") {
+			add(p)
+			continue
+		}
+		var matches []string
+		if strings.Contains(p, "**") {
+			matches = globRecursive(p)
+		} else {
+			matches, _ = filepath.Glob(p)
+		}
+		if len(matches) == 0 {
+			utils.LogMessage("Warning: include pattern %s matched nothing", p)
+			continue
+		}
+		sort.Strings(matches)
+		for _, match := range matches {
+			add(match)
+		}
+	}
+	return out
+}
+
+// globRecursive expands a pattern containing **, which filepath.Glob cannot:
+// it walks from the static directory before the first ** and keeps every
+// path the pattern matches, with ** spanning any number of directories.
+func globRecursive(pattern string) []string {
+	prefix := pattern[:strings.Index(pattern, "**")]
+	// Dir of prefix+"x" strips both a trailing separator and a partial
+	// final segment, leaving the deepest literal directory to walk.
+	root := filepath.Dir(prefix + "x")
+	var matches []string
+	filepath.Walk(root, func(path string, info os.FileInfo, err error) error {
+		if err != nil {
+			return nil
+		}
+		if matchDoublestar(pattern, path) {
+			matches = append(matches, path)
+		}
+		return nil
+	})
+	return matches
+}
+
+// matchDoublestar matches path against pattern segment by segment, with **
+// matching zero or more whole segments and the rest using filepath.Match.
+func matchDoublestar(pattern, path string) bool {
+	sep := string(filepath.Separator)
+	return matchSegments(
+		strings.Split(filepath.Clean(pattern), sep),
+		strings.Split(filepath.Clean(path), sep))
+}
+
+func matchSegments(pat, segs []string) bool {
+	if len(pat) == 0 {
+		return len(segs) == 0
+	}
+	if pat[0] == "**" {
+		for i := 0; i <= len(segs); i++ {
+			if matchSegments(pat[1:], segs[i:]) {
+				return true
+			}
+		}
+		return false
+	}
+	if len(segs) == 0 {
+		return false
+	}
+	ok, err := filepath.Match(pat[0], segs[0])
+	return ok && err == nil && matchSegments(pat[1:], segs[1:])
+}
+
+// filterWithGitCheckIgnore asks git itself which candidate paths the exclude
+// patterns ignore, for exact gitignore semantics. All paths are checked in
+// one batched `git check-ignore --stdin` invocation against a composed
+// ignore file in a throwaway repo.
+func filterWithGitCheckIgnore(files, patterns []string) ([]string, error) {
+	tmp, err := os.MkdirTemp("", "giterdone-checkignore")
+	if err != nil {
+		return nil, err
+	}
+	defer os.RemoveAll(tmp)
+	if out, err := exec.Command("git", "-C", tmp, "init", "--quiet").CombinedOutput(); err != nil {
+		return nil, fmt.Errorf("git init failed: %s: %w", strings.TrimSpace(string(out)), err)
+	}
+	if err := os.WriteFile(filepath.Join(tmp, ".gitignore"), []byte(strings.Join(patterns, "\n")+"\n"), 0o644); err != nil {
+		return nil, err
+	}
+
+	var input strings.Builder
+	for _, f := range files {
+		input.WriteString(strings.TrimPrefix(f, "/"))
+		input.WriteString("\n")
+	}
+	cmd := exec.Command("git", "-C", tmp, "check-ignore", "--stdin")
+	cmd.Stdin = strings.NewReader(input.String())
+	out, err := cmd.Output()
+	if err != nil {
+		// Exit status 1 means no paths were ignored, which is not an error.
+		if exitErr, ok := err.(*exec.ExitError); !ok || exitErr.ExitCode() != 1 {
+			return nil, err
+		}
+	}
+	ignored := make(map[string]bool)
+	for _, line := range strings.Split(string(out), "\n") {
+		if line != "" {
+			ignored[line] = true
+		}
+	}
+	var kept []string
+	for _, f := range files {
+		if ignored[strings.TrimPrefix(f, "/")] {
+			utils.LogMessage("Excluding %s: ignored by git check-ignore", f)
+			continue
+		}
+		kept = append(kept, f)
+	}
+	return kept, nil
+}
+
+// DefaultExcludePatterns returns a copy of the built-in exclude patterns,
+// for callers that write a .gitignore without running a scan.
+func DefaultExcludePatterns() []string {
+	return append([]string{}, excludePatterns...)
+}
+
+// dedupePatterns sorts and deduplicates exclude patterns so the generated
+// .gitignore is byte-stable across runs regardless of walk order.
+func dedupePatterns(patterns []string) []string {
+	seen := make(map[string]bool, len(patterns))
+	var out []string
+	for _, p := range patterns {
+		if !seen[p] {
+			seen[p] = true
+			out = append(out, p)
+		}
+	}
+	sort.Strings(out)
+	return out
+}
+
+// scanPath walks one include path, collecting files to back up and paths to
+// exclude, with its own timing and error handling.
+func scanPath(root string, cfg *config.Config, m *matcher) pathResult {
+	res := pathResult{root: root, patternHits: map[string]bool{}}
+	start := time.Now()
+	m = m.withLocalIgnore(loadLocalIgnore(root))
+	// A first sizing pass lets the walk skip whole directories whose
+	// cumulative contents exceed max_dir_size_mb before descending into
+	// them.
+	var sizes map[string]int64
+	dirLimit := int64(cfg.MaxDirSizeMB) * 1024 * 1024
+	if dirLimit > 0 {
+		sizes = dirSizes(root)
+	}
+	res.err = filepath.Walk(root, func(path string, info os.FileInfo, err error) error {
+		if err != nil {
+			utils.LogMessage("Skipping %s: %v", path, err)
+			return nil
+		}
+		if d := m.matchDecision(info.Name()); d.excluded {
+			if info.IsDir() {
+				// Anchor the skipped directory's ignore entry to its own
+				// path: a bare basename pattern ("node_modules") would also
+				// exclude every same-named directory elsewhere in the repo.
+				res.excludes = append(res.excludes, strings.TrimPrefix(path, "/")+"/")
+				return filepath.SkipDir
+			}
+			if d.layer == "built-in" || d.layer == "config-exclude" {
+				res.patternHits[d.rule] = true
+			}
+			// With git check-ignore enabled, file-level decisions are made
+			// in one batch after the walk for exact gitignore semantics.
+			if !cfg.UseGitCheckIgnore {
+				return nil
+			}
+		}
+		if info.IsDir() {
+			// The include path itself is never skipped for size; silently
+			// backing up nothing would be worse than a big push.
+			if dirLimit > 0 && path != root && sizes[path] > dirLimit {
+				utils.LogMessage("Excluding %s: directory totals %d bytes, over max_dir_size_mb (%dMB)", path, sizes[path], cfg.MaxDirSizeMB)
+				res.excludes = append(res.excludes, strings.TrimPrefix(path, "/")+"/")
+				return filepath.SkipDir
+			}
+			return nil
+		}
+		if limit := sizeLimitFor(path, cfg); info.Size() > limit {
+			utils.LogMessage("Excluding %s: exceeds size limit (%d bytes)", path, info.Size())
+			res.excludes = append(res.excludes, strings.TrimPrefix(path, "/"))
+			return nil
+		} else if limit > globalSizeLimit(cfg) && info.Size() > globalSizeLimit(cfg) {
+			utils.LogMessage("Including %s: size override for its path raises the limit above the default", path)
+		}
+		if cfg.SettleSeconds > 0 && time.Since(info.ModTime()) < time.Duration(cfg.SettleSeconds)*time.Second {
+			utils.LogMessage("Deferring %s: modified within the last %ds, will pick it up next run", path, cfg.SettleSeconds)
+			return nil
+		}
+		res.files = append(res.files, path)
+		return nil
+	})
+	res.duration = time.Since(start)
+	return res
+}
+
+// dirSizes walks root once and returns the cumulative size of regular files
+// under every directory, each file counting toward all of its ancestors up
+// to root.
+func dirSizes(root string) map[string]int64 {
+	sizes := map[string]int64{}
+	filepath.Walk(root, func(path string, info os.FileInfo, err error) error {
+		if err != nil || !info.Mode().IsRegular() {
+			return nil
+		}
+		for dir := filepath.Dir(path); ; dir = filepath.Dir(dir) {
+			sizes[dir] += info.Size()
+			if dir == root || dir == filepath.Dir(dir) {
+				break
+			}
+		}
+		return nil
+	})
+	return sizes
+}
+
+// ListExcluded walks the include paths and returns every excluded file
+// grouped by the reason it was skipped (pattern, size, dir-size,
+// unreadable). Symlinks are not listed: they are backed up either way,
+// as link objects or as their targets' contents depending on
+// follow_symlinks. It performs no git operations and does not modify
+// anything.
+func ListExcluded(cfg *config.Config) (map[string][]string, error) {
+	m := newMatcher(cfg)
+	excluded := map[string][]string{}
+	dirLimit := int64(cfg.MaxDirSizeMB) * 1024 * 1024
+	for _, root := range ExpandIncludePaths(cfg.IncludePaths) {
+		rootMatcher := m.withLocalIgnore(loadLocalIgnore(root))
+		var sizes map[string]int64
+		if dirLimit > 0 {
+			sizes = dirSizes(root)
+		}
+		err := filepath.Walk(root, func(path string, info os.FileInfo, err error) error {
+			if err != nil {
+				excluded["unreadable"] = append(excluded["unreadable"], path)
+				return nil
+			}
+			if d := rootMatcher.matchDecision(info.Name()); d.excluded {
+				reason := fmt.Sprintf("pattern %s (%s)", d.rule, d.layer)
+				excluded[reason] = append(excluded[reason], path)
+				if info.IsDir() {
+					return filepath.SkipDir
+				}
+				return nil
+			}
+			if info.IsDir() {
+				if dirLimit > 0 && path != root && sizes[path] > dirLimit {
+					excluded["dir-size"] = append(excluded["dir-size"], path)
+					return filepath.SkipDir
+				}
+				return nil
+			}
+			if info.Size() > sizeLimitFor(path, cfg) {
+				excluded["size"] = append(excluded["size"], path)
+			}
+			return nil
+		})
+		if err != nil {
+			return nil, fmt.Errorf("could not scan %s: %w", root, err)
+		}
+	}
+	for _, paths := range excluded {
+		sort.Strings(paths)
+	}
+	return excluded, nil
+}
+
+// globalSizeLimit returns the configured per-file size limit, falling back
+// to the built-in default.
+func globalSizeLimit(cfg *config.Config) int64 {
+	if cfg.MaxFileSizeBytes > 0 {
+		return cfg.MaxFileSizeBytes
+	}
+	return maxFileSize
+}
+
+// sizeLimitFor returns the max file size for path, using the longest
+// matching prefix from SizeOverrides (in MB) and falling back to the global
+// limit.
+func sizeLimitFor(path string, cfg *config.Config) int64 {
+	limit := globalSizeLimit(cfg)
+	bestPrefix := -1
+	for prefix, mb := range cfg.SizeOverrides {
+		if strings.HasPrefix(path, prefix) && len(prefix) > bestPrefix {
+			bestPrefix = len(prefix)
+			limit = int64(mb) * 1024 * 1024
+		}
+	}
+	return limit
+}
+
+// compiledPatterns indexes exclude patterns for near-constant matching: a
+// walk calls the matcher once per file, so a linear scan over the pattern
+// slice is O(files x patterns) and dominates large trees. Most patterns are
+// either an exact name ("node_modules") or a bare extension ("*.log"), which
+// map lookups handle directly; anything with richer glob syntax falls back
+// to filepath.Match.
+type compiledPatterns struct {
+	exact map[string]string // exact file or directory name -> pattern
+	byExt map[string]string // extension including the dot -> pattern
+	globs []string          // patterns needing full glob matching
+}
+
+// compiled is built once at startup from excludePatterns.
+var compiled = compilePatterns(excludePatterns)
+
+// compilePatterns sorts patterns into the lookup buckets.
+func compilePatterns(patterns []string) *compiledPatterns {
+	c := &compiledPatterns{
+		exact: make(map[string]string, len(patterns)),
+		byExt: make(map[string]string, len(patterns)),
+	}
+	for _, p := range patterns {
+		switch {
+		case strings.HasPrefix(p, "*.") && !strings.ContainsAny(p[2:], "*?[\\"):
+			c.byExt[p[1:]] = p
+		case !strings.ContainsAny(p, "*?[\\"):
+			c.exact[p] = p
+		default:
+			c.globs = append(c.globs, p)
+		}
+	}
+	return c
+}
+
+// match returns the pattern matching a file or directory name, if any.
+func (c *compiledPatterns) match(name string) (string, bool) {
+	if p, ok := c.exact[name]; ok {
+		return p, true
+	}
+	if ext := filepath.Ext(name); ext != "" {
+		if p, ok := c.byExt[ext]; ok {
+			return p, true
+		}
+	}
+	for _, pattern := range c.globs {
+		if ok, _ := filepath.Match(pattern, name); ok {
+			return pattern, true
+		}
+	}
+	return "", false
+}
+
+// matchedExcludePattern returns the built-in exclude pattern matching a file
+// or directory name.
+func matchedExcludePattern(name string) (string, bool) {
+	return compiled.match(name)
+}
+
+// decision records the outcome of matching one path and the rule that won,
+// so --list-excluded can show why a file was skipped instead of just that it
+// was.
+type decision struct {
+	excluded bool
+	rule     string // the pattern that decided; "" when nothing matched
+	layer    string // the precedence layer the rule came from
+}
+
+// matcher evaluates a path against every exclusion layer in one place.
+// Precedence, highest first:
+//
+//  1. force-include overrides — the user explicitly wants the file
+//  2. local ignore rules — per-tree .giterdoneignore entries
+//  3. user-configured excludes
+//  4. built-in excludes
+//
+// A rule in a higher layer always beats any rule below it, so a
+// force-include keeps a file no matter what would otherwise drop it.
+type matcher struct {
+	forceInclude []string
+	localIgnore  []string
+	userExclude  []string
+	builtin      *compiledPatterns
+}
+
+// newMatcher builds the matcher for a run. The config-supplied layers are
+// populated here; the per-tree local-ignore layer is attached by each walk
+// via withLocalIgnore, and the built-in layer always applies.
+func newMatcher(cfg *config.Config) *matcher {
+	return &matcher{
+		forceInclude: cfg.IncludeOverrides,
+		userExclude:  cfg.ExcludePatterns,
+		builtin:      compiled,
+	}
+}
+
+// localIgnoreName is the per-tree ignore file read from each include path
+// root: one pattern per line, blank lines and # comments skipped.
+const localIgnoreName = ".giterdoneignore"
+
+// loadLocalIgnore reads the include path root's ignore file. A missing
+// file simply yields no rules.
+func loadLocalIgnore(root string) []string {
+	data, err := os.ReadFile(filepath.Join(root, localIgnoreName))
+	if err != nil {
+		return nil
+	}
+	var patterns []string
+	for _, line := range strings.Split(string(data), "\n") {
+		line = strings.TrimSpace(line)
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+		patterns = append(patterns, line)
+	}
+	return patterns
+}
+
+// withLocalIgnore returns a copy of the matcher with the per-tree ignore
+// layer populated, leaving the shared matcher untouched for the other
+// concurrently scanned include paths.
+func (m *matcher) withLocalIgnore(patterns []string) *matcher {
+	if len(patterns) == 0 {
+		return m
+	}
+	clone := *m
+	clone.localIgnore = patterns
+	return &clone
+}
+
+// matchDecision returns whether a file or directory name is excluded and the
+// winning rule. Layers are consulted strictly in precedence order; the first
+// layer with a matching rule decides.
+func (m *matcher) matchDecision(name string) decision {
+	if p, ok := matchAny(m.forceInclude, name); ok {
+		return decision{excluded: false, rule: p, layer: "force-include"}
+	}
+	if p, ok := matchAny(m.localIgnore, name); ok {
+		return decision{excluded: true, rule: p, layer: "local-ignore"}
+	}
+	if p, ok := matchAny(m.userExclude, name); ok {
+		return decision{excluded: true, rule: p, layer: "config-exclude"}
+	}
+	if p, ok := m.builtin.match(name); ok {
+		return decision{excluded: true, rule: p, layer: "built-in"}
+	}
+	return decision{}
+}
+
+// matchAny returns the first pattern in the slice matching name.
+func matchAny(patterns []string, name string) (string, bool) {
+	for _, pattern := range patterns {
+		if ok, _ := filepath.Match(pattern, name); ok {
+			return pattern, true
+		}
+	}
+	return "", false
+}
+
+// Markers delimiting giterdone's block in the repo's .gitignore, so
+// hand-written rules outside the block survive regeneration.
+const (
+	gitignoreBegin = "# BEGIN Giterdone -- generated, do not edit between markers"
+	gitignoreEnd   = "# END Giterdone"
+)
+
+// GenerateGitignoreContent renders giterdone's marker-delimited .gitignore
+// block for the given patterns.
+func GenerateGitignoreContent(patterns []string) string {
+	var sb strings.Builder
+	sb.WriteString(gitignoreBegin + "\n")
+	for _, p := range patterns {
+		sb.WriteString(p)
+		sb.WriteString("\n")
+	}
+	sb.WriteString(gitignoreEnd + "\n")
+	return sb.String()
+}
+
+// WriteGitignoreFile merges the generated block into the repo's .gitignore:
+// only the content between the markers is replaced, so rules the user (or
+// the adopted repo) already had are preserved.
+func WriteGitignoreFile(repoDir string, patterns []string) error {
+	path := filepath.Join(repoDir, ".gitignore")
+	existing, err := os.ReadFile(path)
+	if err != nil && !os.IsNotExist(err) {
+		return err
+	}
+	merged := mergeGitignore(string(existing), GenerateGitignoreContent(patterns))
+	return os.WriteFile(path, []byte(merged), 0o644)
+}
+
+// WriteGitAttributesFile merges a generated block of merge-strategy
+// attributes into the repo's .gitattributes, so append-heavy files (shell
+// history, logs) can use git's union merge driver instead of conflicting
+// across machines. Patterns are emitted sorted for byte-stable output, and
+// hand-written attribute lines survive via the same marker merge the
+// generated .gitignore uses. An empty strategies map is a no-op, leaving
+// any previously generated block in place untouched.
+func WriteGitAttributesFile(repoDir string, strategies map[string]string) error {
+	if len(strategies) == 0 {
+		return nil
+	}
+	patterns := make([]string, 0, len(strategies))
+	for p := range strategies {
+		patterns = append(patterns, p)
+	}
+	sort.Strings(patterns)
+	var sb strings.Builder
+	sb.WriteString(gitignoreBegin + "\n")
+	for _, p := range patterns {
+		sb.WriteString(fmt.Sprintf("%s merge=%s\n", p, strategies[p]))
+	}
+	sb.WriteString(gitignoreEnd + "\n")
+
+	path := filepath.Join(repoDir, ".gitattributes")
+	existing, err := os.ReadFile(path)
+	if err != nil && !os.IsNotExist(err) {
+		return err
+	}
+	merged := mergeGitignore(string(existing), sb.String())
+	return os.WriteFile(path, []byte(merged), 0o644)
+}
+
+// mergeGitignore replaces the marker-delimited block in existing with block,
+// appending the block after the hand-written rules when no markers are
+// present yet.
+func mergeGitignore(existing, block string) string {
+	if strings.TrimSpace(existing) == "" {
+		return block
+	}
+	lines := strings.Split(existing, "\n")
+	begin, end := -1, -1
+	for i, line := range lines {
+		switch {
+		case strings.HasPrefix(line, "# BEGIN Giterdone") && begin == -1:
+			begin = i
+		case strings.HasPrefix(line, gitignoreEnd):
+			end = i
+		}
+	}
+	if begin == -1 || end < begin {
+		return strings.TrimRight(existing, "\n") + "\n\n" + block
+	}
+	var sb strings.Builder
+	if begin > 0 {
+		sb.WriteString(strings.Join(lines[:begin], "\n"))
+		sb.WriteString("\n")
+	}
+	sb.WriteString(block)
+	sb.WriteString(strings.Join(lines[end+1:], "\n"))
+	return sb.String()
+}
@@ -0,0 +1,451 @@
+package scanner
+
+import (
+	"os"
+	"path/filepath"
+	"reflect"
+	"strconv"
+	"strings"
+	"testing"
+
+	"github.com/cdmerchant13/giterdone/config"
+)
+
+func TestDedupePatternsIsDeterministic(t *testing.T) {
+	a := dedupePatterns([]string{"b", "a", "c", "a", "b"})
+	b := dedupePatterns([]string{"c", "b", "a", "b", "c", "a"})
+	if !reflect.DeepEqual(a, b) {
+		t.Errorf("same input in different orders produced different output: %v vs %v", a, b)
+	}
+	want := []string{"a", "b", "c"}
+	if !reflect.DeepEqual(a, want) {
+		t.Errorf("expected sorted deduped patterns %v, got %v", want, a)
+	}
+}
+
+func TestScanFilesEmitsOnlyNeededPatterns(t *testing.T) {
+	dir := t.TempDir()
+	if err := os.WriteFile(filepath.Join(dir, "keep.conf"), []byte("x"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(filepath.Join(dir, "junk.tmp"), []byte("x"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+
+	cfg := &config.Config{IncludePaths: []string{dir}}
+	files, patterns, err := ScanFiles(cfg)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(files) != 1 || filepath.Base(files[0]) != "keep.conf" {
+		t.Errorf("expected only keep.conf to be included, got %v", files)
+	}
+	// *.tmp matched a real file so it must be emitted; unmatched built-ins
+	// like node_modules must not be.
+	got := GenerateGitignoreContent(patterns)
+	if !strings.Contains(got, "*.tmp") {
+		t.Errorf("expected *.tmp in generated gitignore, got:\n%s", got)
+	}
+	if strings.Contains(got, "node_modules") {
+		t.Errorf("unmatched pattern node_modules should be trimmed, got:\n%s", got)
+	}
+}
+
+func TestCompiledPatternsMatchesLinearScan(t *testing.T) {
+	names := []string{
+		"app.log", "file.tmp", "vim.swp", "disk.iso", "root.img",
+		"obj.o", "lib.so", "node_modules", ".cache", ".git",
+		"__pycache__", "keep.conf", "notes.txt", "main.go", "archive.tar.gz",
+	}
+	for _, name := range names {
+		wantPattern, wantOK := "", false
+		for _, pattern := range excludePatterns {
+			if ok, _ := filepath.Match(pattern, name); ok {
+				wantPattern, wantOK = pattern, true
+				break
+			}
+		}
+		gotPattern, gotOK := matchedExcludePattern(name)
+		if gotPattern != wantPattern || gotOK != wantOK {
+			t.Errorf("matchedExcludePattern(%q) = %q, %v; linear scan says %q, %v",
+				name, gotPattern, gotOK, wantPattern, wantOK)
+		}
+	}
+}
+
+// benchmarkNames simulates the name mix of a large tree: mostly kept files
+// with a sprinkling of excluded ones.
+var benchmarkNames = func() []string {
+	names := make([]string, 0, 10000)
+	for i := 0; i < 10000; i++ {
+		switch i % 10 {
+		case 0:
+			names = append(names, "build.log")
+		case 1:
+			names = append(names, "node_modules")
+		default:
+			names = append(names, "source-file-"+strconv.Itoa(i)+".go")
+		}
+	}
+	return names
+}()
+
+func BenchmarkMatchLinear(b *testing.B) {
+	for i := 0; i < b.N; i++ {
+		for _, name := range benchmarkNames {
+			for _, pattern := range excludePatterns {
+				if ok, _ := filepath.Match(pattern, name); ok {
+					break
+				}
+			}
+		}
+	}
+}
+
+func BenchmarkMatchCompiled(b *testing.B) {
+	for i := 0; i < b.N; i++ {
+		for _, name := range benchmarkNames {
+			matchedExcludePattern(name)
+		}
+	}
+}
+
+func TestMatchDecisionPrecedence(t *testing.T) {
+	m := &matcher{
+		forceInclude: []string{"keep.log", "*.pdf"},
+		localIgnore:  []string{"local.conf", "shadowed.txt"},
+		userExclude:  []string{"*.conf", "shadowed.txt", "user.txt"},
+		builtin:      compiled,
+	}
+	cases := []struct {
+		name         string
+		wantExcluded bool
+		wantRule     string
+		wantLayer    string
+	}{
+		// Force-include beats every exclusion layer, including built-ins.
+		{"keep.log", false, "keep.log", "force-include"},
+		{"report.pdf", false, "*.pdf", "force-include"},
+		// Local ignore beats config excludes when both match.
+		{"shadowed.txt", true, "shadowed.txt", "local-ignore"},
+		{"local.conf", true, "local.conf", "local-ignore"},
+		// Config excludes beat built-ins and catch their own patterns.
+		{"app.conf", true, "*.conf", "config-exclude"},
+		{"user.txt", true, "user.txt", "config-exclude"},
+		// Built-ins apply when no higher layer matches.
+		{"debug.log", true, "*.log", "built-in"},
+		{"node_modules", true, "node_modules", "built-in"},
+		// No rule anywhere: included with no winning rule.
+		{"main.go", false, "", ""},
+	}
+	for _, tc := range cases {
+		d := m.matchDecision(tc.name)
+		if d.excluded != tc.wantExcluded || d.rule != tc.wantRule || d.layer != tc.wantLayer {
+			t.Errorf("matchDecision(%q) = {excluded:%v rule:%q layer:%q}, want {excluded:%v rule:%q layer:%q}",
+				tc.name, d.excluded, d.rule, d.layer, tc.wantExcluded, tc.wantRule, tc.wantLayer)
+		}
+	}
+}
+
+func TestSkippedDirPatternsAreAnchored(t *testing.T) {
+	dir := t.TempDir()
+	for _, sub := range []string{"a/node_modules", "b/node_modules"} {
+		if err := os.MkdirAll(filepath.Join(dir, sub), 0o755); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(filepath.Join(dir, sub, "dep.js"), []byte("x"), 0o644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := os.WriteFile(filepath.Join(dir, "keep.conf"), []byte("x"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+
+	cfg := &config.Config{IncludePaths: []string{dir}}
+	files, patterns, err := ScanFiles(cfg)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(files) != 1 {
+		t.Errorf("expected only keep.conf to be included, got %v", files)
+	}
+	rel := strings.TrimPrefix(dir, "/")
+	for _, want := range []string{rel + "/a/node_modules/", rel + "/b/node_modules/"} {
+		found := false
+		for _, p := range patterns {
+			if p == want {
+				found = true
+			}
+		}
+		if !found {
+			t.Errorf("expected anchored pattern %q, got %v", want, patterns)
+		}
+	}
+	// The bare basename must not be emitted: it would over-exclude any
+	// same-named directory elsewhere.
+	for _, p := range patterns {
+		if p == "node_modules" {
+			t.Errorf("bare node_modules pattern emitted alongside anchored entries: %v", patterns)
+		}
+	}
+}
+
+func TestMaxDirSizeSkipsDirectory(t *testing.T) {
+	dir := t.TempDir()
+	big := filepath.Join(dir, "big")
+	if err := os.MkdirAll(big, 0o755); err != nil {
+		t.Fatal(err)
+	}
+	// Many small files that collectively exceed the 1MB limit.
+	chunk := make([]byte, 300*1024)
+	for i := 0; i < 4; i++ {
+		if err := os.WriteFile(filepath.Join(big, "part-"+strconv.Itoa(i)), chunk, 0o644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := os.WriteFile(filepath.Join(dir, "keep.conf"), []byte("x"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+
+	cfg := &config.Config{IncludePaths: []string{dir}, MaxDirSizeMB: 1}
+	files, patterns, err := ScanFiles(cfg)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(files) != 1 || filepath.Base(files[0]) != "keep.conf" {
+		t.Errorf("expected only keep.conf to survive, got %v", files)
+	}
+	wantEntry := strings.TrimPrefix(big, "/") + "/"
+	found := false
+	for _, p := range patterns {
+		if p == wantEntry {
+			found = true
+		}
+	}
+	if !found {
+		t.Errorf("expected anchored exclude %q in patterns %v", wantEntry, patterns)
+	}
+}
+
+func TestUserExcludesAndIncludeOverrides(t *testing.T) {
+	dir := t.TempDir()
+	for _, name := range []string{"keep.conf", "secret.env", "manual.pdf", "junk.tmp"} {
+		if err := os.WriteFile(filepath.Join(dir, name), []byte("x"), 0o644); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	cfg := &config.Config{
+		IncludePaths:     []string{dir},
+		ExcludePatterns:  []string{"*.env", "*.pdf"},
+		IncludeOverrides: []string{"manual.pdf"},
+	}
+	files, patterns, err := ScanFiles(cfg)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	got := map[string]bool{}
+	for _, f := range files {
+		got[filepath.Base(f)] = true
+	}
+	if !got["keep.conf"] || got["secret.env"] || got["junk.tmp"] {
+		t.Errorf("unexpected file selection: %v", files)
+	}
+	if !got["manual.pdf"] {
+		t.Errorf("include override should have kept manual.pdf: %v", files)
+	}
+
+	patternSet := map[string]bool{}
+	for _, p := range patterns {
+		patternSet[p] = true
+	}
+	if !patternSet["*.env"] {
+		t.Errorf("expected matched user pattern *.env in .gitignore patterns %v", patterns)
+	}
+	if patternSet["*.pdf"] {
+		t.Errorf("*.pdf only matched a force-included file; it should not reach .gitignore: %v", patterns)
+	}
+}
+
+func TestWriteGitignoreFilePreservesHandWrittenRules(t *testing.T) {
+	dir := t.TempDir()
+	path := filepath.Join(dir, ".gitignore")
+	if err := os.WriteFile(path, []byte("# mine\nvendor/\n"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := WriteGitignoreFile(dir, []string{"*.log"}); err != nil {
+		t.Fatal(err)
+	}
+	first, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, want := range []string{"vendor/", "*.log", gitignoreBegin, gitignoreEnd} {
+		if !strings.Contains(string(first), want) {
+			t.Errorf("expected %q in merged gitignore:\n%s", want, first)
+		}
+	}
+
+	// A second run must replace only the block, not stack a new one.
+	if err := WriteGitignoreFile(dir, []string{"*.tmp"}); err != nil {
+		t.Fatal(err)
+	}
+	second, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !strings.Contains(string(second), "vendor/") || !strings.Contains(string(second), "*.tmp") {
+		t.Errorf("expected hand-written rules and new block, got:\n%s", second)
+	}
+	if strings.Contains(string(second), "*.log") {
+		t.Errorf("old block content should be replaced, got:\n%s", second)
+	}
+	if strings.Count(string(second), gitignoreBegin) != 1 {
+		t.Errorf("expected exactly one generated block, got:\n%s", second)
+	}
+}
+
+func TestExpandIncludePaths(t *testing.T) {
+	dir := t.TempDir()
+	for _, p := range []string{
+		"app1/settings.json",
+		"app2/settings.json",
+		"app2/other.txt",
+		"deep/a/b/conf.toml",
+		"conf.toml",
+	} {
+		full := filepath.Join(dir, p)
+		if err := os.MkdirAll(filepath.Dir(full), 0o755); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(full, []byte("x"), 0o644); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	got := ExpandIncludePaths([]string{
+		filepath.Join(dir, "*", "settings.json"),
+		filepath.Join(dir, "**", "*.toml"),
+		filepath.Join(dir, "app1", "settings.json"), // duplicate of the glob match
+		filepath.Join(dir, "nomatch-*"),             // warns, never errors
+		filepath.Join(dir, "app2"),                  // literal passes through
+	})
+	want := []string{
+		filepath.Join(dir, "app1", "settings.json"),
+		filepath.Join(dir, "app2", "settings.json"),
+		filepath.Join(dir, "conf.toml"),
+		filepath.Join(dir, "deep", "a", "b", "conf.toml"),
+		filepath.Join(dir, "app2"),
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("expected %v, got %v", want, got)
+	}
+}
+
+func TestMatchDoublestar(t *testing.T) {
+	cases := []struct {
+		pattern, path string
+		want          bool
+	}{
+		{"/a/**/*.toml", "/a/x.toml", true},
+		{"/a/**/*.toml", "/a/b/c/x.toml", true},
+		{"/a/**/*.toml", "/a/b/x.json", false},
+		{"/a/*/x", "/a/b/c/x", false},
+		{"/a/**", "/a/b/c", true},
+	}
+	for _, c := range cases {
+		if got := matchDoublestar(c.pattern, c.path); got != c.want {
+			t.Errorf("matchDoublestar(%q, %q) = %v, want %v", c.pattern, c.path, got, c.want)
+		}
+	}
+}
+
+func TestScanFilesDeduplicatesOverlappingIncludes(t *testing.T) {
+	dir := t.TempDir()
+	path := filepath.Join(dir, "keep.conf")
+	if err := os.WriteFile(path, []byte("x"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+
+	// The directory and a file inside it both scan keep.conf.
+	cfg := &config.Config{IncludePaths: []string{dir, path}}
+	files, _, err := ScanFiles(cfg)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(files) != 1 || files[0] != path {
+		t.Errorf("expected exactly one deduplicated entry for %s, got %v", path, files)
+	}
+}
+
+func TestWriteGitAttributesFile(t *testing.T) {
+	dir := t.TempDir()
+	path := filepath.Join(dir, ".gitattributes")
+	if err := os.WriteFile(path, []byte("*.bin binary\n"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+
+	strategies := map[string]string{".bash_history": "union", "logs/*.log": "union"}
+	if err := WriteGitAttributesFile(dir, strategies); err != nil {
+		t.Fatal(err)
+	}
+	got, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, want := range []string{"*.bin binary", ".bash_history merge=union", "logs/*.log merge=union"} {
+		if !strings.Contains(string(got), want) {
+			t.Errorf("expected %q in merged gitattributes:\n%s", want, got)
+		}
+	}
+
+	// A second run must replace the block, not stack another.
+	if err := WriteGitAttributesFile(dir, map[string]string{"*.log": "union"}); err != nil {
+		t.Fatal(err)
+	}
+	second, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if strings.Contains(string(second), ".bash_history") {
+		t.Errorf("old block content should be replaced, got:\n%s", second)
+	}
+	if strings.Count(string(second), gitignoreBegin) != 1 {
+		t.Errorf("expected exactly one generated block, got:\n%s", second)
+	}
+}
+
+func TestGiterdoneignoreFileIsHonored(t *testing.T) {
+	dir := t.TempDir()
+	for name, content := range map[string]string{
+		"keep.conf":     "x",
+		"secret.conf":   "x",
+		localIgnoreName: "# local rules\nsecret.conf\n",
+	} {
+		if err := os.WriteFile(filepath.Join(dir, name), []byte(content), 0o644); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	cfg := &config.Config{IncludePaths: []string{dir}}
+	files, _, err := ScanFiles(cfg)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, f := range files {
+		if filepath.Base(f) == "secret.conf" {
+			t.Errorf("secret.conf should be dropped by %s, got %v", localIgnoreName, files)
+		}
+	}
+	found := false
+	for _, f := range files {
+		if filepath.Base(f) == "keep.conf" {
+			found = true
+		}
+	}
+	if !found {
+		t.Errorf("keep.conf should survive, got %v", files)
+	}
+}
@@ -0,0 +1,114 @@
+package main
+
+import (
+	"fmt"
+	"math"
+	"os"
+	"regexp"
+
+	"github.com/cdmerchant13/giterdone/config"
+	"github.com/cdmerchant13/giterdone/utils"
+)
+
+// secretPatterns are the known secret shapes checked before commit. Matches
+// are reported by pattern name only; the matched text itself is never
+// logged or printed.
+var secretPatterns = []struct {
+	name string
+	re   *regexp.Regexp
+}{
+	{"AWS access key", regexp.MustCompile(`AKIA[0-9A-Z]{16}`)},
+	{"private key header", regexp.MustCompile(`-----BEGIN (RSA |EC |DSA |OPENSSH |PGP )?PRIVATE KEY( BLOCK)?-----`)},
+	{"GitHub token", regexp.MustCompile(`gh[pousr]_[A-Za-z0-9]{36,}`)},
+	{"GitLab token", regexp.MustCompile(`glpat-[A-Za-z0-9_-]{20,}`)},
+	{"Slack token", regexp.MustCompile(`xox[baprs]-[A-Za-z0-9-]{10,}`)},
+}
+
+// entropyCandidate matches long unbroken tokens worth an entropy check.
+var entropyCandidate = regexp.MustCompile(`[A-Za-z0-9+/=_-]{40,}`)
+
+// secretScanMaxFileSize bounds how much of a file the secret scan reads;
+// anything larger is scanned only up to this prefix.
+const secretScanMaxFileSize = 1 << 20 // 1MB
+
+// effectiveSecretScanPolicy resolves the secret scan policy: the configured
+// value wins; unset defaults to "abort" for public repos (where a leak is
+// immediately world-readable) and "warn" otherwise.
+func effectiveSecretScanPolicy(cfg *config.Config) string {
+	if cfg.SecretScanPolicy != "" {
+		return cfg.SecretScanPolicy
+	}
+	if cfg.RepoVisibility == "public" {
+		return "abort"
+	}
+	return "warn"
+}
+
+// scanFilesForSecrets checks every included file for known secret patterns
+// and high-entropy strings before commit. Per the policy it warns about
+// findings or aborts the run; "off" skips the scan entirely.
+func scanFilesForSecrets(cfg *config.Config, files []string) error {
+	policy := effectiveSecretScanPolicy(cfg)
+	if policy == "off" {
+		return nil
+	}
+	findings := 0
+	for _, path := range files {
+		for _, name := range scanFileForSecrets(path) {
+			utils.LogMessage("Possible secret in %s: %s", path, name)
+			findings++
+		}
+	}
+	if findings == 0 {
+		return nil
+	}
+	if policy == "abort" {
+		return fmt.Errorf("secret scan found %d possible secrets (see log); set secret_scan_policy to \"warn\" or \"off\" to proceed anyway", findings)
+	}
+	utils.LogMessage("Warning: %d possible secrets will be committed (secret_scan_policy: %s)", findings, policy)
+	return nil
+}
+
+// scanFileForSecrets returns the names of the patterns matched in one file.
+func scanFileForSecrets(path string) []string {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil
+	}
+	defer f.Close()
+	buf := make([]byte, secretScanMaxFileSize)
+	n, _ := f.Read(buf)
+	content := string(buf[:n])
+
+	var matched []string
+	for _, p := range secretPatterns {
+		if p.re.MatchString(content) {
+			matched = append(matched, p.name)
+		}
+	}
+	for _, token := range entropyCandidate.FindAllString(content, -1) {
+		if shannonEntropy(token) > 4.5 {
+			matched = append(matched, "high-entropy string")
+			break
+		}
+	}
+	return matched
+}
+
+// shannonEntropy returns the per-character entropy of s in bits; random
+// base64 material scores near 6, prose well under 4.5.
+func shannonEntropy(s string) float64 {
+	if s == "" {
+		return 0
+	}
+	counts := map[rune]int{}
+	for _, r := range s {
+		counts[r]++
+	}
+	entropy := 0.0
+	for _, c := range counts {
+		p := float64(c) / float64(len(s))
+		entropy -= p * math.Log2(p)
+	}
+	return entropy
+}
@@ -0,0 +1,429 @@
+package main
+
+import (
+	"archive/tar"
+	"bytes"
+	"compress/gzip"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/cdmerchant13/giterdone/config"
+	"github.com/cdmerchant13/giterdone/scanner"
+	"github.com/cdmerchant13/giterdone/utils"
+)
+
+// storageBackend is how non-git destinations plug in: each backup uploads
+// one encrypted archive, and restore retrieves the latest one. The
+// release-asset and s3 modes are the two implementations.
+type storageBackend interface {
+	// Upload stores the encrypted archive under the given name.
+	Upload(archive, name string) error
+	// DownloadLatest fetches the most recent archive into a temp file and
+	// returns its path along with a label describing what was fetched; the
+	// caller removes the file.
+	DownloadLatest() (string, string, error)
+}
+
+// storageBackendFor returns the backend for the configured storage mode,
+// or nil for the default git-file model.
+func storageBackendFor(cfg *config.Config) (storageBackend, error) {
+	switch cfg.StorageMode {
+	case "release-asset":
+		if cfg.EffectiveProvider() != "github" {
+			return nil, fmt.Errorf("storage_mode %q requires a GitHub remote", cfg.StorageMode)
+		}
+		if cfg.PAT == "" {
+			return nil, fmt.Errorf("storage_mode %q requires a PAT to call the GitHub API", cfg.StorageMode)
+		}
+		return &releaseAssetBackend{cfg: cfg}, nil
+	case "s3":
+		if cfg.S3Bucket == "" {
+			return nil, fmt.Errorf("storage_mode \"s3\" requires s3_bucket to be set")
+		}
+		if _, err := exec.LookPath("aws"); err != nil {
+			return nil, fmt.Errorf("storage_mode \"s3\" requires the aws CLI on PATH")
+		}
+		return &s3Backend{cfg: cfg}, nil
+	default:
+		return nil, nil
+	}
+}
+
+// runArchiveBackup implements the archive storage modes: instead of
+// committing file contents, the included files are tarred, encrypted with
+// gpg, and uploaded via the backend. The destination then holds opaque
+// encrypted blobs rather than browsable files.
+func runArchiveBackup(cfg *config.Config, dryRun string, backend storageBackend) error {
+	files, _, err := scanner.ScanFiles(cfg)
+	if err != nil {
+		return err
+	}
+	lastRun.filesScanned = len(files)
+	if dryRun == "offline" {
+		fmt.Printf("Dry run: would archive and upload %d files:\n", len(files))
+		for _, f := range files {
+			fmt.Println("  " + f)
+		}
+		return nil
+	}
+
+	archive, err := createEncryptedArchive(files)
+	if err != nil {
+		return err
+	}
+	defer os.Remove(archive)
+
+	if dryRun == "remote" {
+		info, err := os.Stat(archive)
+		if err != nil {
+			return err
+		}
+		fmt.Printf("Dry run: would upload a %s encrypted archive of %d files.\n",
+			utils.HumanSize(info.Size()), len(files))
+		return nil
+	}
+
+	name := "backup-" + time.Now().Format("20060102-150405")
+	if err := backend.Upload(archive, name); err != nil {
+		return err
+	}
+	lastRun.pushed = true
+	utils.LogMessage("Uploaded encrypted archive of %d files as %s", len(files), name)
+	return nil
+}
+
+// createEncryptedArchive tars and gzips the files, then encrypts the archive
+// with gpg to the user's own key (matching the encrypted-config handling).
+// It returns the path of the encrypted temp file; the caller removes it.
+func createEncryptedArchive(files []string) (string, error) {
+	plain, err := os.CreateTemp("", "giterdone-archive-*.tar.gz")
+	if err != nil {
+		return "", err
+	}
+	defer os.Remove(plain.Name())
+
+	gz := gzip.NewWriter(plain)
+	tw := tar.NewWriter(gz)
+	for _, path := range files {
+		if err := addFileToArchive(tw, path); err != nil {
+			utils.LogMessage("Could not archive %s: %v", path, err)
+		}
+	}
+	if err := tw.Close(); err != nil {
+		return "", err
+	}
+	if err := gz.Close(); err != nil {
+		return "", err
+	}
+	if err := plain.Close(); err != nil {
+		return "", err
+	}
+
+	encrypted := plain.Name() + ".gpg"
+	cmd := exec.Command("gpg", "--quiet", "--yes", "--encrypt", "--default-recipient-self",
+		"--output", encrypted, plain.Name())
+	var stderr bytes.Buffer
+	cmd.Stderr = &stderr
+	if err := cmd.Run(); err != nil {
+		return "", fmt.Errorf("could not encrypt archive (is the gpg agent running?): %s: %w",
+			strings.TrimSpace(stderr.String()), err)
+	}
+	return encrypted, nil
+}
+
+// addFileToArchive writes one file into the tar, stored by its absolute path
+// with the leading slash stripped, mirroring the repo layout.
+func addFileToArchive(tw *tar.Writer, path string) error {
+	info, err := os.Stat(path)
+	if err != nil {
+		return err
+	}
+	hdr, err := tar.FileInfoHeader(info, "")
+	if err != nil {
+		return err
+	}
+	hdr.Name = strings.TrimPrefix(path, "/")
+	if err := tw.WriteHeader(hdr); err != nil {
+		return err
+	}
+	f, err := os.Open(path)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+	_, err = io.Copy(tw, f)
+	return err
+}
+
+// githubRepoPath extracts "owner/repo" from the configured GitHub URL.
+func githubRepoPath(cfg *config.Config) (string, error) {
+	repoPath := strings.TrimSuffix(strings.TrimPrefix(cfg.RepoURL, "https://github.com/"), ".git")
+	if parts := strings.SplitN(repoPath, "/", 2); len(parts) != 2 || parts[1] == "" {
+		return "", fmt.Errorf("could not parse owner/repo from %s", cfg.SanitizedRepoURL())
+	}
+	return repoPath, nil
+}
+
+// githubAPIRequest performs one authenticated GitHub API call and returns
+// the response; the caller closes the body.
+func githubAPIRequest(cfg *config.Config, method, url, contentType string, body io.Reader) (*http.Response, error) {
+	req, err := http.NewRequest(method, url, body)
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("Authorization", "Bearer "+cfg.PAT)
+	req.Header.Set("Accept", "application/vnd.github+json")
+	if contentType != "" {
+		req.Header.Set("Content-Type", contentType)
+	}
+	client := &http.Client{Timeout: 5 * time.Minute}
+	return client.Do(req)
+}
+
+// releaseAssetBackend stores archives as GitHub release assets, one release
+// per backup tagged by timestamp.
+type releaseAssetBackend struct {
+	cfg *config.Config
+}
+
+// Upload creates a release for the tag and attaches the encrypted archive
+// to it.
+func (b *releaseAssetBackend) Upload(archive, tag string) error {
+	cfg := b.cfg
+	repoPath, err := githubRepoPath(cfg)
+	if err != nil {
+		return err
+	}
+
+	body, _ := json.Marshal(map[string]interface{}{
+		"tag_name": tag,
+		"name":     tag,
+	})
+	resp, err := githubAPIRequest(cfg, "POST",
+		"https://api.github.com/repos/"+repoPath+"/releases", "application/json", bytes.NewReader(body))
+	if err != nil {
+		return fmt.Errorf("could not create release: %w", err)
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusCreated {
+		return fmt.Errorf("GitHub API release creation failed with status %s", resp.Status)
+	}
+	var release struct {
+		ID int64 `json:"id"`
+	}
+	if err := json.NewDecoder(resp.Body).Decode(&release); err != nil {
+		return err
+	}
+
+	f, err := os.Open(archive)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+	uploadURL := fmt.Sprintf("https://uploads.github.com/repos/%s/releases/%d/assets?name=%s.tar.gz.gpg",
+		repoPath, release.ID, tag)
+	upResp, err := githubAPIRequest(cfg, "POST", uploadURL, "application/octet-stream", f)
+	if err != nil {
+		return fmt.Errorf("could not upload release asset: %w", err)
+	}
+	defer upResp.Body.Close()
+	if upResp.StatusCode != http.StatusCreated {
+		return fmt.Errorf("GitHub API asset upload failed with status %s", upResp.Status)
+	}
+	return nil
+}
+
+// DownloadLatest fetches the newest release's asset into a temp file.
+func (b *releaseAssetBackend) DownloadLatest() (string, string, error) {
+	cfg := b.cfg
+	repoPath, err := githubRepoPath(cfg)
+	if err != nil {
+		return "", "", err
+	}
+	resp, err := githubAPIRequest(cfg, "GET",
+		"https://api.github.com/repos/"+repoPath+"/releases/latest", "", nil)
+	if err != nil {
+		return "", "", err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return "", "", fmt.Errorf("could not find latest release (status %s)", resp.Status)
+	}
+	var release struct {
+		TagName string `json:"tag_name"`
+		Assets  []struct {
+			URL string `json:"url"`
+		} `json:"assets"`
+	}
+	if err := json.NewDecoder(resp.Body).Decode(&release); err != nil {
+		return "", "", err
+	}
+	if len(release.Assets) == 0 {
+		return "", "", fmt.Errorf("release %s has no assets", release.TagName)
+	}
+
+	req, err := http.NewRequest("GET", release.Assets[0].URL, nil)
+	if err != nil {
+		return "", "", err
+	}
+	req.Header.Set("Authorization", "Bearer "+cfg.PAT)
+	req.Header.Set("Accept", "application/octet-stream")
+	client := &http.Client{Timeout: 5 * time.Minute}
+	assetResp, err := client.Do(req)
+	if err != nil {
+		return "", "", err
+	}
+	defer assetResp.Body.Close()
+	if assetResp.StatusCode != http.StatusOK {
+		return "", "", fmt.Errorf("could not download release asset (status %s)", assetResp.Status)
+	}
+
+	encrypted, err := os.CreateTemp("", "giterdone-restore-*.tar.gz.gpg")
+	if err != nil {
+		return "", "", err
+	}
+	if _, err := io.Copy(encrypted, assetResp.Body); err != nil {
+		encrypted.Close()
+		os.Remove(encrypted.Name())
+		return "", "", err
+	}
+	if err := encrypted.Close(); err != nil {
+		os.Remove(encrypted.Name())
+		return "", "", err
+	}
+	return encrypted.Name(), "release "+release.TagName, nil
+}
+
+// s3Backend stores archives in an S3-compatible bucket under a fixed key,
+// relying on bucket versioning to keep history. It drives the aws CLI the
+// same way gpg and crontab are driven, so any S3-compatible endpoint the
+// CLI can reach works.
+type s3Backend struct {
+	cfg *config.Config
+}
+
+// objectURL returns the s3:// URL of the backup object.
+func (b *s3Backend) objectURL() string {
+	key := b.cfg.S3Key
+	if key == "" {
+		key = "giterdone-backup.tar.gz.gpg"
+	}
+	return "s3://" + b.cfg.S3Bucket + "/" + key
+}
+
+// awsArgs prepends the endpoint override for S3-compatible stores.
+func (b *s3Backend) awsArgs(args ...string) []string {
+	if b.cfg.S3Endpoint != "" {
+		return append([]string{"--endpoint-url", b.cfg.S3Endpoint}, args...)
+	}
+	return args
+}
+
+// Upload copies the encrypted archive over the bucket's backup object; a
+// versioned bucket retains the previous backups.
+func (b *s3Backend) Upload(archive, name string) error {
+	out, err := exec.Command("aws", b.awsArgs("s3", "cp", archive, b.objectURL())...).CombinedOutput()
+	if err != nil {
+		return fmt.Errorf("s3 upload failed: %s: %w", strings.TrimSpace(string(out)), err)
+	}
+	return nil
+}
+
+// DownloadLatest fetches the bucket's current backup object.
+func (b *s3Backend) DownloadLatest() (string, string, error) {
+	tmp, err := os.CreateTemp("", "giterdone-restore-*.tar.gz.gpg")
+	if err != nil {
+		return "", "", err
+	}
+	tmp.Close()
+	out, err := exec.Command("aws", b.awsArgs("s3", "cp", b.objectURL(), tmp.Name())...).CombinedOutput()
+	if err != nil {
+		os.Remove(tmp.Name())
+		return "", "", fmt.Errorf("s3 download failed: %s: %w", strings.TrimSpace(string(out)), err)
+	}
+	return tmp.Name(), b.objectURL(), nil
+}
+
+// runArchiveRestore downloads the latest archive via the backend, decrypts
+// it, and unpacks it back to the filesystem. With only set, just paths under
+// those prefixes are extracted.
+func runArchiveRestore(cfg *config.Config, only []string, backend storageBackend) error {
+	encrypted, label, err := backend.DownloadLatest()
+	if err != nil {
+		return err
+	}
+	defer os.Remove(encrypted)
+
+	cmd := exec.Command("gpg", "--quiet", "--decrypt", encrypted)
+	var stderr bytes.Buffer
+	cmd.Stderr = &stderr
+	decrypted, err := cmd.Output()
+	if err != nil {
+		return fmt.Errorf("could not decrypt archive (is the gpg agent running?): %s: %w",
+			strings.TrimSpace(stderr.String()), err)
+	}
+
+	restored, err := extractArchive(bytes.NewReader(decrypted), only)
+	if err != nil {
+		return err
+	}
+	fmt.Printf("Restored %d files from %s.\n", restored, label)
+	return nil
+}
+
+// extractArchive unpacks a gzipped tar back to absolute paths, optionally
+// filtered to the given path prefixes.
+func extractArchive(r io.Reader, only []string) (int, error) {
+	gz, err := gzip.NewReader(r)
+	if err != nil {
+		return 0, err
+	}
+	defer gz.Close()
+	tr := tar.NewReader(gz)
+	restored := 0
+	for {
+		hdr, err := tr.Next()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return restored, err
+		}
+		dest := "/" + hdr.Name
+		if len(only) > 0 && !matchesAnyPrefix(dest, only) {
+			continue
+		}
+		if err := os.MkdirAll(filepath.Dir(dest), 0o755); err != nil {
+			return restored, err
+		}
+		f, err := os.OpenFile(dest, os.O_WRONLY|os.O_CREATE|os.O_TRUNC, os.FileMode(hdr.Mode))
+		if err != nil {
+			utils.LogMessage("Could not restore %s: %v", dest, err)
+			continue
+		}
+		if _, err := io.Copy(f, tr); err != nil {
+			f.Close()
+			return restored, err
+		}
+		f.Close()
+		restored++
+	}
+	return restored, nil
+}
+
+// matchesAnyPrefix reports whether path falls under any of the prefixes.
+func matchesAnyPrefix(path string, prefixes []string) bool {
+	for _, p := range prefixes {
+		if strings.HasPrefix(path, p) {
+			return true
+		}
+	}
+	return false
+}
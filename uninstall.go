@@ -0,0 +1,76 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"runtime"
+
+	"github.com/urfave/cli/v2"
+
+	"github.com/cdmerchant13/giterdone/config"
+	"github.com/cdmerchant13/giterdone/cron"
+	"github.com/cdmerchant13/giterdone/utils"
+)
+
+// runUninstall removes the installed cron job and, with --purge, deletes the
+// config directory (config, state, logs, and the local repo clone). It is
+// idempotent: with nothing installed it reports so and exits cleanly.
+func runUninstall(c *cli.Context) error {
+	assumeYes = c.Bool("assume-yes")
+	configDir, err := config.GetConfigDir()
+	if err != nil {
+		return err
+	}
+	if err := utils.InitLogger(configDir, c.Bool("verbose")); err != nil {
+		return err
+	}
+
+	// The scheduler backend comes from the config when it is still
+	// readable; otherwise fall back to the cron default.
+	scheduler := ""
+	if cfg, err := config.LoadConfig(); err == nil {
+		scheduler = cfg.Scheduler
+	}
+	// Mirror SchedulerFor's platform default so the right backend is
+	// cleaned up when the config never named one.
+	if scheduler == "" && runtime.GOOS == "darwin" {
+		scheduler = "launchd"
+	}
+	if scheduler == "" || scheduler == "cron" {
+		entry, err := cron.RemoveCronJob()
+		if err != nil {
+			return err
+		}
+		if entry == "" {
+			fmt.Println("No cron job installed; nothing to remove.")
+		} else {
+			fmt.Printf("Removed cron job: %s\n", entry)
+		}
+	} else {
+		sched, err := cron.SchedulerFor(scheduler)
+		if err != nil {
+			return err
+		}
+		if err := sched.Remove(); err != nil {
+			return err
+		}
+		fmt.Println("Removed scheduled job.")
+	}
+
+	if !c.Bool("purge") {
+		return nil
+	}
+	ok, err := confirm(fmt.Sprintf("Delete %s (config, state, logs, and the local repo clone)", configDir))
+	if err != nil {
+		return err
+	}
+	if !ok {
+		fmt.Println("Purge skipped.")
+		return nil
+	}
+	if err := os.RemoveAll(configDir); err != nil {
+		return err
+	}
+	fmt.Printf("Deleted %s. The remote repo is untouched.\n", configDir)
+	return nil
+}
@@ -0,0 +1,313 @@
+package utils
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"os/user"
+	"path/filepath"
+	"regexp"
+	"runtime"
+	"strconv"
+	"strings"
+	"sync"
+	"text/template"
+	"time"
+)
+
+var (
+	logFile *os.File
+	logPath string
+	verbose bool
+	// logMu serializes rotation and writes: scanner workers log from
+	// multiple goroutines, and rotation swaps the logFile handle.
+	logMu sync.Mutex
+)
+
+// Rotation defaults: a 5-minute cron cadence writes a few lines per run, so
+// 3 files of 5MB bounds the footprint without losing recent history.
+var (
+	logMaxSizeBytes int64 = 5 << 20
+	logMaxBackups         = 3
+)
+
+// SetLogRotation overrides the rotation thresholds; zero values keep the
+// defaults.
+func SetLogRotation(maxSizeBytes int64, maxBackups int) {
+	if maxSizeBytes > 0 {
+		logMaxSizeBytes = maxSizeBytes
+	}
+	if maxBackups > 0 {
+		logMaxBackups = maxBackups
+	}
+}
+
+// InitLogger opens (or creates) the giterdone log file inside the config
+// directory and remembers whether verbose output was requested.
+func InitLogger(configDir string, verboseFlag bool) error {
+	verbose = verboseFlag
+	if err := os.MkdirAll(configDir, 0o755); err != nil {
+		return fmt.Errorf("could not create config directory: %w", err)
+	}
+	logPath = filepath.Join(configDir, "giterdone.log")
+	rotateLogIfNeeded()
+	f, err := os.OpenFile(logPath, os.O_APPEND|os.O_CREATE|os.O_WRONLY, 0o644)
+	if err != nil {
+		return fmt.Errorf("could not open log file: %w", err)
+	}
+	logFile = f
+	return nil
+}
+
+// rotateLogIfNeeded shifts giterdone.log to giterdone.log.1 (and so on, up
+// to logMaxBackups) once it exceeds the size limit, so a long-running cron
+// setup never fills the disk. Any open handle must be closed first.
+func rotateLogIfNeeded() {
+	info, err := os.Stat(logPath)
+	if err != nil || info.Size() < logMaxSizeBytes {
+		return
+	}
+	os.Remove(fmt.Sprintf("%s.%d", logPath, logMaxBackups))
+	for i := logMaxBackups - 1; i >= 1; i-- {
+		os.Rename(fmt.Sprintf("%s.%d", logPath, i), fmt.Sprintf("%s.%d", logPath, i+1))
+	}
+	os.Rename(logPath, logPath+".1")
+}
+
+// logFormat selects the log file encoding: "text" (the default) or "json"
+// for one object per line, parseable by log shippers. Console echo stays
+// human-readable either way.
+var logFormat string
+
+// SetLogFormat selects the log file encoding.
+func SetLogFormat(format string) error {
+	switch format {
+	case "", "text", "json":
+		logFormat = format
+		return nil
+	default:
+		return fmt.Errorf("invalid log_format %q (expected text or json)", format)
+	}
+}
+
+// urlCredentials matches user:secret@ userinfo embedded in any URL.
+var urlCredentials = regexp.MustCompile(`(://)[^/@\s]+@`)
+
+// registeredSecrets holds literal values (like the configured PAT) that must
+// never appear in logs or error output.
+var registeredSecrets []string
+
+// RegisterSecret records a literal value to be masked by Redact. Empty
+// values are ignored.
+func RegisterSecret(v string) {
+	if v != "" {
+		registeredSecrets = append(registeredSecrets, v)
+	}
+}
+
+// Redact masks URL-embedded credentials and every registered secret with
+// ***. All log output passes through it, since giterdone.log often lives in
+// a directory that itself gets backed up.
+func Redact(s string) string {
+	s = urlCredentials.ReplaceAllString(s, "${1}***@")
+	for _, secret := range registeredSecrets {
+		s = strings.ReplaceAll(s, secret, "***")
+	}
+	return s
+}
+
+// LogMessage writes an info-level line to the log file and, when verbose
+// mode is on, echoes it to stdout. Messages starting with a "Warning:" or
+// "Error:" prefix are promoted to the matching level.
+func LogMessage(format string, args ...interface{}) {
+	msg := fmt.Sprintf(format, args...)
+	level := "info"
+	for prefix, l := range map[string]string{"Warning: ": "warn", "WARNING: ": "warn", "Error: ": "error", "ERROR: ": "error"} {
+		if strings.HasPrefix(msg, prefix) {
+			level, msg = l, strings.TrimPrefix(msg, prefix)
+			break
+		}
+	}
+	logAt(level, msg)
+}
+
+// LogWarning writes a warn-level line.
+func LogWarning(format string, args ...interface{}) {
+	logAt("warn", fmt.Sprintf(format, args...))
+}
+
+// LogError writes an error-level line.
+func LogError(format string, args ...interface{}) {
+	logAt("error", fmt.Sprintf(format, args...))
+}
+
+// logAt writes one log record at the given level, in the configured file
+// format, echoing a human-readable form to stdout in verbose mode.
+func logAt(level, msg string) {
+	msg = Redact(msg)
+	now := time.Now()
+	logMu.Lock()
+	defer logMu.Unlock()
+	// Rotate mid-run too, so a single very chatty run cannot grow the file
+	// unbounded between InitLogger calls.
+	if logFile != nil {
+		if info, err := logFile.Stat(); err == nil && info.Size() >= logMaxSizeBytes {
+			logFile.Close()
+			logFile = nil
+			rotateLogIfNeeded()
+			if f, err := os.OpenFile(logPath, os.O_APPEND|os.O_CREATE|os.O_WRONLY, 0o644); err == nil {
+				logFile = f
+			}
+		}
+	}
+	if logFile != nil {
+		if logFormat == "json" {
+			record, err := json.Marshal(struct {
+				TS    string `json:"ts"`
+				Level string `json:"level"`
+				Msg   string `json:"msg"`
+			}{now.Format(time.RFC3339), level, msg})
+			if err == nil {
+				fmt.Fprintln(logFile, string(record))
+			}
+		} else {
+			prefix := ""
+			if level != "info" {
+				prefix = strings.ToUpper(level) + ": "
+			}
+			fmt.Fprintf(logFile, "[%s] %s%s\n", now.Format("2006-01-02 15:04:05"), prefix, msg)
+		}
+	}
+	if verbose {
+		if level != "info" {
+			fmt.Println(strings.ToUpper(level) + ": " + msg)
+		} else {
+			fmt.Println(msg)
+		}
+	}
+}
+
+// DefaultCommitTemplate is the template shipped in the binary, used when no
+// template is configured and offered as the wizard's default, so fresh
+// installs produce useful messages without any configuration.
+const DefaultCommitTemplate = `Backup from {{.Hostname}} at {{.Timestamp.Format "2006-01-02 15:04:05"}}`
+
+// GenerateCommitMessage renders the configured commit message template,
+// falling back to DefaultCommitTemplate when it is empty. Templates have
+// access to {{.Timestamp}}, {{.Hostname}}, {{.User}}, {{.OS}} and
+// {{.FileCount}} (the number of files scanned this run), plus the helper
+// funcs {{now}} (RFC 3339 timestamp) and {{shortdate}} (YYYY-MM-DD).
+// Hostname and user lookups that fail render as empty strings rather than
+// failing the backup.
+func GenerateCommitMessage(tmplStr string, fileCount int) (string, error) {
+	if tmplStr == "" {
+		tmplStr = DefaultCommitTemplate
+	}
+	tmpl, err := template.New("commit").Funcs(template.FuncMap{
+		"now":       func() string { return time.Now().Format(time.RFC3339) },
+		"shortdate": func() string { return time.Now().Format("2006-01-02") },
+	}).Parse(tmplStr)
+	if err != nil {
+		return "", fmt.Errorf("invalid commit message template: %w", err)
+	}
+	hostname, _ := os.Hostname()
+	username := ""
+	if u, err := user.Current(); err == nil {
+		username = u.Username
+	}
+	var sb strings.Builder
+	err = tmpl.Execute(&sb, struct {
+		Timestamp time.Time
+		Hostname  string
+		User      string
+		OS        string
+		FileCount int
+	}{Timestamp: time.Now(), Hostname: hostname, User: username, OS: runtime.GOOS, FileCount: fileCount})
+	if err != nil {
+		return "", fmt.Errorf("could not render commit message: %w", err)
+	}
+	return sb.String(), nil
+}
+
+// ExpandPathTemplate renders a path template with {{.Year}}, {{.Month}},
+// {{.Day}} and {{.Hostname}} tokens (date parts zero-padded), the same
+// text/template machinery commit messages use.
+func ExpandPathTemplate(tmplStr string) (string, error) {
+	tmpl, err := template.New("path").Parse(tmplStr)
+	if err != nil {
+		return "", fmt.Errorf("invalid path template: %w", err)
+	}
+	now := time.Now()
+	hostname, _ := os.Hostname()
+	var sb strings.Builder
+	err = tmpl.Execute(&sb, struct {
+		Year, Month, Day, Hostname string
+	}{now.Format("2006"), now.Format("01"), now.Format("02"), hostname})
+	if err != nil {
+		return "", fmt.Errorf("could not render path template: %w", err)
+	}
+	return sb.String(), nil
+}
+
+// DirSize returns the total size in bytes of all regular files under root.
+func DirSize(root string) (int64, error) {
+	var total int64
+	err := filepath.Walk(root, func(path string, info os.FileInfo, err error) error {
+		if err != nil {
+			return nil
+		}
+		if info.Mode().IsRegular() {
+			total += info.Size()
+		}
+		return nil
+	})
+	return total, err
+}
+
+// HumanSize renders a byte count as a short human-readable string.
+func HumanSize(n int64) string {
+	const unit = 1024
+	if n < unit {
+		return fmt.Sprintf("%dB", n)
+	}
+	div, exp := int64(unit), 0
+	for v := n / unit; v >= unit; v /= unit {
+		div *= unit
+		exp++
+	}
+	return fmt.Sprintf("%.1f%cB", float64(n)/float64(div), "KMGTPE"[exp])
+}
+
+// ParseHumanSize parses a human-friendly size like "50MB", "2GB" or a bare
+// byte count into bytes, the inverse of HumanSize's rendering.
+func ParseHumanSize(s string) (int64, error) {
+	s = strings.TrimSpace(strings.ToUpper(s))
+	multiplier := int64(1)
+	for _, unit := range []struct {
+		suffix string
+		factor int64
+	}{
+		{"TB", 1 << 40}, {"GB", 1 << 30}, {"MB", 1 << 20}, {"KB", 1 << 10}, {"B", 1},
+	} {
+		if strings.HasSuffix(s, unit.suffix) {
+			multiplier = unit.factor
+			s = strings.TrimSpace(strings.TrimSuffix(s, unit.suffix))
+			break
+		}
+	}
+	n, err := strconv.ParseFloat(s, 64)
+	if err != nil || n < 0 {
+		return 0, fmt.Errorf("invalid size %q (expected e.g. 50MB or 2GB)", s)
+	}
+	return int64(n * float64(multiplier)), nil
+}
+
+// IsInteractive reports whether stdin is attached to a terminal. Prompting
+// code must check this so a scheduled run can never hang waiting for input.
+func IsInteractive() bool {
+	info, err := os.Stdin.Stat()
+	if err != nil {
+		return false
+	}
+	return info.Mode()&os.ModeCharDevice != 0
+}
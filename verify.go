@@ -0,0 +1,99 @@
+package main
+
+import (
+	"fmt"
+	"path/filepath"
+
+	"github.com/urfave/cli/v2"
+
+	"github.com/cdmerchant13/giterdone/config"
+	"github.com/cdmerchant13/giterdone/git"
+	"github.com/cdmerchant13/giterdone/manifest"
+	"github.com/cdmerchant13/giterdone/utils"
+)
+
+// runManifestVerify cross-checks the manifest against the repo's tracked
+// files, reporting orphans (manifest entries with no file) and untracked
+// files (files with no manifest entry). With --fix the manifest is
+// reconciled to match the repo.
+func runManifestVerify(c *cli.Context) error {
+	configDir, err := config.GetConfigDir()
+	if err != nil {
+		return err
+	}
+	if err := utils.InitLogger(configDir, c.Bool("verbose")); err != nil {
+		return err
+	}
+	repoDir, err := config.GetRepoDir()
+	if err != nil {
+		return err
+	}
+	if !git.IsGitRepo(repoDir) {
+		return fmt.Errorf("no local backup repo at %s", repoDir)
+	}
+
+	m, err := manifest.Load(repoDir)
+	if err != nil {
+		return err
+	}
+	tracked, err := git.LsFiles(repoDir)
+	if err != nil {
+		return err
+	}
+	trackedSet := make(map[string]bool, len(tracked))
+	for _, f := range tracked {
+		trackedSet[f] = true
+	}
+
+	var orphans, untracked []string
+	for rel, entry := range m.Entries {
+		// Duplicates are intentionally not stored as files.
+		if entry.DuplicateOf != "" {
+			continue
+		}
+		if !trackedSet[rel] {
+			orphans = append(orphans, rel)
+		}
+	}
+	for _, rel := range tracked {
+		if rel == ".gitignore" || rel == manifest.FileName {
+			continue
+		}
+		if _, ok := m.Entries[rel]; !ok {
+			untracked = append(untracked, rel)
+		}
+	}
+
+	if len(orphans) == 0 && len(untracked) == 0 {
+		fmt.Println("Manifest is consistent with the repo.")
+		return nil
+	}
+	for _, rel := range orphans {
+		fmt.Printf("orphan: %s (in manifest, not in repo)\n", rel)
+	}
+	for _, rel := range untracked {
+		fmt.Printf("untracked: %s (in repo, not in manifest)\n", rel)
+	}
+
+	if !c.Bool("fix") {
+		fmt.Println("Run with --fix to reconcile the manifest.")
+		return nil
+	}
+	for _, rel := range orphans {
+		delete(m.Entries, rel)
+	}
+	for _, rel := range untracked {
+		hash, err := manifest.HashFileWith(filepath.Join(repoDir, rel), m.Algorithm)
+		if err != nil {
+			utils.LogMessage("Could not hash %s: %v", rel, err)
+			continue
+		}
+		m.Entries[rel] = manifest.Entry{Hash: hash}
+	}
+	if err := m.Save(repoDir); err != nil {
+		return err
+	}
+	fmt.Printf("Reconciled manifest: removed %d orphans, added %d entries. The updated manifest will be committed on the next backup.\n",
+		len(orphans), len(untracked))
+	return nil
+}
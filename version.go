@@ -0,0 +1,31 @@
+package main
+
+import (
+	"fmt"
+	"runtime"
+
+	"github.com/urfave/cli/v2"
+)
+
+// Build metadata, overridden at release time via
+// -ldflags "-X main.version=... -X main.commit=... -X main.date=...".
+// The defaults identify a from-source build so bug reports are never blank.
+var (
+	version = "dev"
+	commit  = "unknown"
+	date    = "unknown"
+)
+
+// versionString is what urfave/cli prints after "giterdone version".
+func versionString() string {
+	return fmt.Sprintf("%s (commit %s, built %s, %s, %s/%s)",
+		version, commit, date, runtime.Version(), runtime.GOOS, runtime.GOARCH)
+}
+
+func init() {
+	// -v already means --verbose here, so the version flag gets no alias.
+	cli.VersionFlag = &cli.BoolFlag{Name: "version", Usage: "print the version"}
+	cli.VersionPrinter = func(c *cli.Context) {
+		fmt.Printf("giterdone version %s\n", versionString())
+	}
+}
@@ -0,0 +1,125 @@
+package main
+
+import (
+	"encoding/base64"
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"strings"
+	"syscall"
+
+	"github.com/cdmerchant13/giterdone/utils"
+)
+
+// xattrManifestName is the sidecar file inside the backup repo recording
+// each file's extended attributes, which git itself does not preserve.
+const xattrManifestName = ".giterdone-xattrs.json"
+
+// captureXattrs records the extended attributes of every included file into
+// the sidecar manifest and returns its repo-relative name for staging, or ""
+// when no file carried any attributes. Filesystems without xattr support
+// degrade gracefully: the file is skipped and logged.
+func captureXattrs(repoDir string, files []string) (string, error) {
+	attrs := map[string]map[string]string{}
+	for _, path := range files {
+		fileAttrs, err := fileXattrs(path)
+		if err != nil {
+			utils.LogMessage("Skipping xattrs for %s: %v", path, err)
+			continue
+		}
+		if len(fileAttrs) == 0 {
+			continue
+		}
+		encoded := make(map[string]string, len(fileAttrs))
+		for name, value := range fileAttrs {
+			encoded[name] = base64.StdEncoding.EncodeToString(value)
+		}
+		attrs[strings.TrimPrefix(path, "/")] = encoded
+	}
+	if len(attrs) == 0 {
+		return "", nil
+	}
+	data, err := json.MarshalIndent(attrs, "", "  ")
+	if err != nil {
+		return "", err
+	}
+	if err := os.WriteFile(filepath.Join(repoDir, xattrManifestName), data, 0o644); err != nil {
+		return "", err
+	}
+	utils.LogMessage("Captured xattrs for %d files", len(attrs))
+	return xattrManifestName, nil
+}
+
+// fileXattrs returns one file's extended attributes. A filesystem that does
+// not support xattrs yields an empty map, not an error.
+func fileXattrs(path string) (map[string][]byte, error) {
+	buf := make([]byte, 4096)
+	n, err := syscall.Listxattr(path, buf)
+	for err == syscall.ERANGE {
+		buf = make([]byte, len(buf)*2)
+		n, err = syscall.Listxattr(path, buf)
+	}
+	if err == syscall.ENOTSUP || err == syscall.EOPNOTSUPP {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	attrs := map[string][]byte{}
+	for _, name := range strings.Split(strings.TrimRight(string(buf[:n]), "\x00"), "\x00") {
+		if name == "" {
+			continue
+		}
+		value := make([]byte, 4096)
+		vn, err := syscall.Getxattr(path, name, value)
+		for err == syscall.ERANGE {
+			value = make([]byte, len(value)*2)
+			vn, err = syscall.Getxattr(path, name, value)
+		}
+		if err != nil {
+			utils.LogMessage("Could not read xattr %s of %s: %v", name, path, err)
+			continue
+		}
+		attrs[name] = value[:vn]
+	}
+	return attrs, nil
+}
+
+// applyXattrs reapplies the attributes recorded in the repo's sidecar
+// manifest to the restored files. A missing sidecar means there is nothing
+// to do.
+func applyXattrs(repoDir string) {
+	data, err := os.ReadFile(filepath.Join(repoDir, xattrManifestName))
+	if os.IsNotExist(err) {
+		return
+	} else if err != nil {
+		utils.LogMessage("Could not read xattr manifest: %v", err)
+		return
+	}
+	var attrs map[string]map[string]string
+	if err := json.Unmarshal(data, &attrs); err != nil {
+		utils.LogMessage("Could not parse xattr manifest: %v", err)
+		return
+	}
+	applied := 0
+	for rel, fileAttrs := range attrs {
+		dest := "/" + rel
+		if _, err := os.Lstat(dest); err != nil {
+			continue
+		}
+		for name, encoded := range fileAttrs {
+			value, err := base64.StdEncoding.DecodeString(encoded)
+			if err != nil {
+				continue
+			}
+			if err := syscall.Setxattr(dest, name, value, 0); err != nil {
+				utils.LogMessage("Could not set xattr %s on %s: %v", name, dest, err)
+				continue
+			}
+			applied++
+		}
+	}
+	if applied > 0 {
+		utils.LogMessage("Reapplied %d xattrs", applied)
+	}
+}